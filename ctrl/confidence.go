@@ -0,0 +1,69 @@
+package ctrl
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger"
+)
+
+const (
+	// The confidence factor of the mappings derived from the transformed values, against the exactly matched ones.
+	transformConfidenceFactor = 0.8
+	// The confidence factor of the mappings derived from the composite values, against the exactly matched ones.
+	compositeConfidenceFactor = 0.7
+	// The confidence of the mappings derived from the vibration diffs.
+	vibrationConfidence = 1.0
+	// The confidence of the mappings derived from the vibration property set changes.
+	conditionalVibrationConfidence = 0.8
+)
+
+// valueConfidence scores how likely a value matches another one by chance (in the string representation of JSONValueValueMap).
+// The short or low-entropy values (e.g. booleans, small numbers, single letter strings) get low scores.
+func valueConfidence(val string) float64 {
+	if val == "TRUE" || val == "FALSE" {
+		return 0.2
+	}
+	runes := map[rune]bool{}
+	for _, r := range val {
+		runes[r] = true
+	}
+	score := 0.3 + 0.1*float64(len(runes))
+	if _, err := strconv.ParseFloat(val, 64); err == nil {
+		score = math.Min(score-0.1, 0.6)
+	}
+	return roundConfidence(math.Min(score, 1))
+}
+
+func roundConfidence(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// withProvenance returns a copy of the JSONValuePos with the provenance and the confidence set, or nil if the JSONValuePos is nil.
+func withProvenance(pos *swagger.JSONValuePos, provenance string, confidence float64) *swagger.JSONValuePos {
+	if pos == nil {
+		return nil
+	}
+	npos := *pos
+	npos.Provenance = provenance
+	npos.Confidence = roundConfidence(confidence)
+	return &npos
+}
+
+// FilterConfidence removes the mappings whose confidence is lower than the threshold.
+// The app properties that have no mapping left are removed.
+func (mm ModelMap) FilterConfidence(threshold float64) ModelMap {
+	result := ModelMap{}
+	for k, poses := range mm {
+		var l []*swagger.JSONValuePos
+		for _, pos := range poses {
+			if pos.Confidence >= threshold {
+				l = append(l, pos)
+			}
+		}
+		if len(l) != 0 {
+			result[k] = l
+		}
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package ctrl
+
+import (
+	"testing"
+
+	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueConfidence(t *testing.T) {
+	cases := []struct {
+		val    string
+		expect float64
+	}{
+		{val: "TRUE", expect: 0.2},
+		{val: "1", expect: 0.3},
+		{val: "1234567890", expect: 0.6},
+		{val: "a", expect: 0.4},
+		{val: "aaaa", expect: 0.4},
+		{val: "eastus", expect: 0.8},
+		{val: "/subscriptions/xxx/resourceGroups/rg", expect: 1},
+	}
+	for _, tt := range cases {
+		t.Run(tt.val, func(t *testing.T) {
+			require.Equal(t, tt.expect, valueConfidence(tt.val))
+		})
+	}
+}
+
+func TestModelMapAddConfidence(t *testing.T) {
+	addr := swagger.MustParseAddr("location")
+	weak := &swagger.JSONValuePos{Addr: addr, Provenance: swagger.ProvenanceValueMatch, Confidence: 0.4}
+	strong := &swagger.JSONValuePos{Addr: addr, Provenance: swagger.ProvenanceVibration, Confidence: 1}
+	require.Equal(t, ModelMap{"/location": {strong}}, ModelMap{"/location": {weak}}.Add(ModelMap{"/location": {strong}}))
+	require.Equal(t, ModelMap{"/location": {strong}}, ModelMap{"/location": {strong}}.Add(ModelMap{"/location": {weak}}))
+}
+
+func TestModelMapFilterConfidence(t *testing.T) {
+	weak := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("enabled"), Confidence: 0.2}
+	strong := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("name"), Confidence: 0.8}
+	input := ModelMap{
+		"/enabled": {weak},
+		"/name":    {strong},
+		"/id":      {weak, strong},
+	}
+	require.Equal(t, ModelMap{
+		"/name": {strong},
+		"/id":   {strong},
+	}, input.FilterConfidence(0.5))
+	require.Equal(t, input, input.FilterConfidence(0))
+}
@@ -8,23 +8,27 @@ type Config struct {
 }
 
 type Override struct {
-	PathPattern           string             `hcl:"path_pattern,attr"`
-	RequestModify         *RequestDescriptor `hcl:"request_modify,block"`
-	ResponseSelectorMerge string             `hcl:"response_selector_merge,optional"`
-	ResponseSelectorJSON  string             `hcl:"response_selector_json,optional"`
-	ResponseBody          string             `hcl:"response_body,optional"`
-	ResponsePatchMerge    string             `hcl:"response_patch_merge,optional"`
-	ResponsePatchJSON     string             `hcl:"response_patch_json,optional"`
-	ResponseHeader        map[string]string  `hcl:"response_header,optional"`
-	ResponseStatusCode    int                `hcl:"response_status_code,optional"`
-	ExpanderOption        *ExpanderOption    `hcl:"expander,block"`
-	SynthOption           *SynthOption       `hcl:"synthesizer,block"`
+	PathPattern             string             `hcl:"path_pattern,attr"`
+	RequestModify           *RequestDescriptor `hcl:"request_modify,block"`
+	ResponseSelectorMerge   string             `hcl:"response_selector_merge,optional"`
+	ResponseSelectorJSON    string             `hcl:"response_selector_json,optional"`
+	ResponseSelectorVariant []string           `hcl:"response_selector_variant,optional"`
+	ResponseBody            string             `hcl:"response_body,optional"`
+	ResponsePatchMerge      string             `hcl:"response_patch_merge,optional"`
+	ResponsePatchJSON       string             `hcl:"response_patch_json,optional"`
+	ResponseHeader          map[string]string  `hcl:"response_header,optional"`
+	ResponseStatusCode      int                `hcl:"response_status_code,optional"`
+	MaxInstances            int                `hcl:"max_instances,optional"`
+	ExpanderOption          *ExpanderOption    `hcl:"expander,block"`
+	SynthOption             *SynthOption       `hcl:"synthesizer,block"`
 }
 
 type Vibration struct {
 	PathPattern string    `hcl:"path_pattern,attr"`
-	Path        string    `hcl:"path,attr"`
-	Value       cty.Value `hcl:"value,attr"`
+	Path        string    `hcl:"path,optional"`
+	Addr        string    `hcl:"addr,optional"`
+	Op          string    `hcl:"op,optional"`
+	Value       cty.Value `hcl:"value,optional"`
 }
 
 type Execution struct {
@@ -39,6 +43,13 @@ type Execution struct {
 	Dir        string            `hcl:"dir,optional"`
 	Path       string            `hcl:"path,attr"`
 	Args       []string          `hcl:"args,optional"`
+	Output     *Output           `hcl:"output,block"`
+}
+
+type Output struct {
+	Format   string `hcl:"format,optional"`
+	Resource string `hcl:"resource,optional"`
+	Query    string `hcl:"query,optional"`
 }
 
 func (exec Execution) String() string {
@@ -46,13 +57,28 @@ func (exec Execution) String() string {
 }
 
 type SynthOption struct {
-	UseEnumValue     bool               `hcl:"use_enum_value,optional"`
-	DuplicateElement []DuplicateElement `hcl:"duplicate_element,block"`
+	UseEnumValue         bool               `hcl:"use_enum_value,optional"`
+	ArrayElementVariants bool               `hcl:"array_element_variants,optional"`
+	DuplicateElement     []DuplicateElement `hcl:"duplicate_element,block"`
+	Value                []SynthValue       `hcl:"value,block"`
+	MapKey               []SynthMapKey      `hcl:"map_key,block"`
+	NullableAsNull       bool               `hcl:"nullable_as_null,optional"`
+	OmitOptional         bool               `hcl:"omit_optional,optional"`
+	UseDefault           bool               `hcl:"use_default,optional"`
+	Access               string             `hcl:"access,optional"`
+	Behavior             []SynthBehavior    `hcl:"behavior,block"`
 }
 
 type ExpanderOption struct {
-	EmptyObjAsStr bool `hcl:"empty_obj_as_str,optional"`
-	DisableCache  bool `hcl:"disable_cache,optional"`
+	EmptyObjAsStr     bool                `hcl:"empty_obj_as_str,optional"`
+	DisableCache      bool                `hcl:"disable_cache,optional"`
+	RecursionDepth    int                 `hcl:"recursion_depth,optional"`
+	RefRecursionDepth []RefRecursionDepth `hcl:"ref_recursion_depth,block"`
+}
+
+type RefRecursionDepth struct {
+	Ref   string `hcl:"ref,attr"`
+	Depth int    `hcl:"depth,attr"`
 }
 
 type DuplicateElement struct {
@@ -60,6 +86,30 @@ type DuplicateElement struct {
 	Addr  string `hcl:"addr,attr"`
 }
 
+type SynthValue struct {
+	Addr      string    `hcl:"addr,attr"`
+	Value     cty.Value `hcl:"value,optional"`
+	EnumIndex *int      `hcl:"enum_index,optional"`
+	Format    string    `hcl:"format,optional"`
+	Prefix    string    `hcl:"prefix,optional"`
+}
+
+type SynthMapKey struct {
+	Addr   string   `hcl:"addr,attr"`
+	Keys   []string `hcl:"keys,optional"`
+	Format string   `hcl:"format,optional"`
+	Unique bool     `hcl:"unique,optional"`
+	Prefix string   `hcl:"prefix,optional"`
+}
+
+type SynthBehavior struct {
+	Addr           string `hcl:"addr,attr"`
+	NullableAsNull bool   `hcl:"nullable_as_null,optional"`
+	OmitOptional   bool   `hcl:"omit_optional,optional"`
+	UseDefault     bool   `hcl:"use_default,optional"`
+	Access         string `hcl:"access,optional"`
+}
+
 type RequestDescriptor struct {
 	Method  string `hcl:"method,optional"`
 	Path    string `hcl:"path,optional"`
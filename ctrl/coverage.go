@@ -0,0 +1,225 @@
+package ctrl
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger"
+)
+
+const (
+	CoverageFormatJSON     = "json"
+	CoverageFormatMarkdown = "markdown"
+)
+
+// CoverageReport maps the execution name to its coverage.
+type CoverageReport map[string]*Coverage
+
+// Coverage tells which API properties are (not) surfaced by the application, and which application properties are not mapped to any API property.
+type Coverage struct {
+	// APIProperties are the leaf properties of the expanded response models of the called operations, sorted by their root models and addresses.
+	APIProperties []CoverageAPIProperty `json:"api_properties"`
+	// UnmappedAppProperties are the (generalized) pointers of the application properties that are not mapped to any API property.
+	UnmappedAppProperties []string `json:"unmapped_app_properties"`
+}
+
+type CoverageAPIProperty struct {
+	RootModel swagger.RootModelInfo `json:"root_model"`
+	Ref       string                `json:"ref"`
+	Addr      string                `json:"addr"`
+	Mapped    bool                  `json:"mapped"`
+	// AppProperties are the (generalized) pointers of the application properties that are mapped to this API property.
+	AppProperties []string `json:"app_properties,omitempty"`
+}
+
+func (p CoverageAPIProperty) key() string {
+	return p.RootModel.String() + ":" + p.Addr
+}
+
+// NewCoverage builds the coverage of an execution from its model map, the (generalized) pointers of its application properties,
+// and the expanded response models of the called operations.
+// The model map is expected to be built from the same response models, and not yet made relative to the specdir.
+func NewCoverage(mm ModelMap, appProps []string, models []*swagger.Property, specdir string) (*Coverage, error) {
+	mapped := map[string][]string{}
+	for appProp, poses := range mm {
+		for _, pos := range poses {
+			mapped[pos.String()] = append(mapped[pos.String()], appProp)
+		}
+	}
+
+	cov := &Coverage{}
+	seen := map[string]bool{}
+	for _, model := range models {
+		if model == nil {
+			continue
+		}
+		for _, pos := range model.LeafPositions() {
+			if seen[pos.String()] {
+				continue
+			}
+			seen[pos.String()] = true
+			rootModel := pos.RootModel
+			if ref := rootModel.PathRef; ref.GetURL() != nil && ref.GetURL().Path != "" {
+				path, err := filepath.Rel(specdir, ref.GetURL().Path)
+				if err != nil {
+					return nil, err
+				}
+				rootModel.PathRef = jsonreference.MustCreateRef(path + "#" + ref.GetPointer().String())
+			}
+			ref := pos.Ref.String()
+			if u := pos.Ref.GetURL(); u != nil && u.Path != "" {
+				path, err := filepath.Rel(specdir, u.Path)
+				if err != nil {
+					return nil, err
+				}
+				ref = path + "#" + pos.Ref.GetPointer().String()
+			}
+			mappedProps := mapped[pos.String()]
+			sort.Strings(mappedProps)
+			cov.APIProperties = append(cov.APIProperties, CoverageAPIProperty{
+				RootModel:     rootModel,
+				Ref:           ref,
+				Addr:          pos.Addr.String(),
+				Mapped:        len(mappedProps) != 0,
+				AppProperties: mappedProps,
+			})
+		}
+	}
+	cov.sortAPIProperties()
+
+	for _, appProp := range appProps {
+		if _, ok := mm[appProp]; !ok {
+			cov.UnmappedAppProperties = append(cov.UnmappedAppProperties, appProp)
+		}
+	}
+	cov.UnmappedAppProperties = uniqueSorted(cov.UnmappedAppProperties)
+	return cov, nil
+}
+
+// Merge merges two coverages of the same execution name. An API property is regarded as mapped if it is mapped in either coverage,
+// and an application property is regarded as unmapped only if it is not mapped in any coverage.
+func (cov *Coverage) Merge(ocov *Coverage) *Coverage {
+	if cov == nil {
+		return ocov
+	}
+	if ocov == nil {
+		return cov
+	}
+	result := &Coverage{}
+	index := map[string]int{}
+	mappedAppProps := map[string]bool{}
+	for _, p := range append(append([]CoverageAPIProperty{}, cov.APIProperties...), ocov.APIProperties...) {
+		for _, appProp := range p.AppProperties {
+			mappedAppProps[appProp] = true
+		}
+		i, ok := index[p.key()]
+		if !ok {
+			index[p.key()] = len(result.APIProperties)
+			p.AppProperties = append([]string{}, p.AppProperties...)
+			result.APIProperties = append(result.APIProperties, p)
+			continue
+		}
+		rp := &result.APIProperties[i]
+		rp.Mapped = rp.Mapped || p.Mapped
+		rp.AppProperties = uniqueSorted(append(rp.AppProperties, p.AppProperties...))
+	}
+	for i := range result.APIProperties {
+		if len(result.APIProperties[i].AppProperties) == 0 {
+			result.APIProperties[i].AppProperties = nil
+		}
+	}
+	result.sortAPIProperties()
+
+	for _, appProp := range append(append([]string{}, cov.UnmappedAppProperties...), ocov.UnmappedAppProperties...) {
+		if !mappedAppProps[appProp] {
+			result.UnmappedAppProperties = append(result.UnmappedAppProperties, appProp)
+		}
+	}
+	result.UnmappedAppProperties = uniqueSorted(result.UnmappedAppProperties)
+	return result
+}
+
+func (cov *Coverage) sortAPIProperties() {
+	sort.SliceStable(cov.APIProperties, func(i, j int) bool {
+		pi, pj := cov.APIProperties[i], cov.APIProperties[j]
+		if pi.RootModel.String() != pj.RootModel.String() {
+			return pi.RootModel.String() < pj.RootModel.String()
+		}
+		return pi.Addr < pj.Addr
+	})
+}
+
+// Format formats the coverage report in the specified format.
+func (report CoverageReport) Format(format string) ([]byte, error) {
+	switch format {
+	case "", CoverageFormatJSON:
+		return json.MarshalIndent(report, "", "  ")
+	case CoverageFormatMarkdown:
+		return []byte(report.Markdown()), nil
+	default:
+		return nil, fmt.Errorf("unknown coverage format %q", format)
+	}
+}
+
+// Markdown renders the coverage report as a Markdown document.
+func (report CoverageReport) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("# Coverage\n")
+	for _, name := range sortedKeys(report) {
+		cov := report[name]
+		var nMapped int
+		for _, p := range cov.APIProperties {
+			if p.Mapped {
+				nMapped++
+			}
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n", name)
+		fmt.Fprintf(&sb, "%d of %d API properties are mapped.\n\n", nMapped, len(cov.APIProperties))
+		sb.WriteString("### API Properties\n\n")
+		sb.WriteString("| Operation | Property | Mapped | App Properties |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, p := range cov.APIProperties {
+			mapped := ""
+			if p.Mapped {
+				mapped = "yes"
+			}
+			var appProps []string
+			for _, appProp := range p.AppProperties {
+				appProps = append(appProps, "`"+appProp+"`")
+			}
+			fmt.Fprintf(&sb, "| %s %s (%s) | `%s` | %s | %s |\n", strings.ToUpper(p.RootModel.Operation), apiPath(p.RootModel), p.RootModel.Version, p.Addr, mapped, strings.Join(appProps, ", "))
+		}
+		sb.WriteString("\n### Unmapped App Properties\n\n")
+		if len(cov.UnmappedAppProperties) == 0 {
+			sb.WriteString("None\n")
+		}
+		for _, appProp := range cov.UnmappedAppProperties {
+			fmt.Fprintf(&sb, "- `%s`\n", appProp)
+		}
+	}
+	return sb.String()
+}
+
+// apiPath returns the API path of the root model, or its path ref if it doesn't refer to a path.
+func apiPath(info swagger.RootModelInfo) string {
+	tks := info.PathRef.GetPointer().DecodedTokens()
+	if len(tks) == 2 && tks[0] == "paths" {
+		return tks[1]
+	}
+	return info.PathRef.String()
+}
+
+func uniqueSorted(l []string) []string {
+	if l == nil {
+		return nil
+	}
+	m := map[string]bool{}
+	for _, v := range l {
+		m[v] = true
+	}
+	return sortedKeys(m)
+}
@@ -0,0 +1,140 @@
+package ctrl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/go-openapi/spec"
+	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCoverageUnmappedAppProperties(t *testing.T) {
+	mm := ModelMap{
+		"/name": {{Addr: swagger.MustParseAddr("name")}},
+	}
+	cov, err := NewCoverage(mm, []string{"/name", "/tags/*", "/tags/*", "/id"}, nil, "/specdir")
+	require.NoError(t, err)
+	require.Equal(t, &Coverage{
+		UnmappedAppProperties: []string{"/id", "/tags/*"},
+	}, cov)
+}
+
+func TestNewCoverage(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	specdir := filepath.Join(pwd, "..", "mockserver", "testdata")
+	exp, err := swagger.NewExpanderFromOpRef(spec.MustCreateRef(filepath.Join(specdir, "foo.json")+"#/paths/~1subscriptions~1{subscriptionId}~1resourceGroups~1{resourceGroupName}~1providers~1Microsoft.Foo~1foos~1{fooName}/get"), nil)
+	require.NoError(t, err)
+	require.NoError(t, exp.Expand())
+
+	record, err := swagger.UnmarshalJSONToJSONValue([]byte(`{
+  "id": "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Foo/foos/foo1",
+  "name": "foo1",
+  "properties": {
+    "count": 12345,
+    "rules": ["rule1", "rule2"],
+    "tags": {"KEY": "tag1"},
+    "pet": {"kind": "Dog", "bark": "woof"}
+  }
+}`), exp.Root())
+	require.NoError(t, err)
+
+	appModel := map[string]interface{}{
+		"resource_group_name": "rg1",
+		"rules":               []interface{}{"rule1", "rule2"},
+		"bark":                "woof",
+		"extra":               "unknown",
+	}
+	m, err := MapSingleAppModel(appModel, nil, record)
+	require.NoError(t, err)
+	mm, conflicts := m.ToModelMap().GeneralizePointers(appModel, swagger.JSONValueMapKeys(record))
+	require.Empty(t, conflicts)
+	var appProps []string
+	for ptr := range flattenJSON(appModel) {
+		appProps = append(appProps, generalizePointer(ptr, appModel, nil))
+	}
+
+	cov, err := NewCoverage(mm, appProps, []*swagger.Property{exp.Root()}, specdir)
+	require.NoError(t, err)
+
+	rootModel := swagger.RootModelInfo{
+		PathRef:   jsonreference.MustCreateRef("foo.json#/paths/~1subscriptions~1{subscriptionId}~1resourceGroups~1{resourceGroupName}~1providers~1Microsoft.Foo~1foos~1{fooName}"),
+		Operation: "get",
+		Version:   "2023-01-01",
+	}
+	require.Equal(t, &Coverage{
+		APIProperties: []CoverageAPIProperty{
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/id", Addr: "id", Mapped: true, AppProperties: []string{"/resource_group_name"}},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/name", Addr: "name"},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/FooProperties/properties/count", Addr: "properties/count"},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Pet/properties/kind", Addr: "properties/pet{Cat}/kind"},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Cat/properties/meow", Addr: "properties/pet{Cat}/meow"},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Dog/properties/bark", Addr: "properties/pet{Dog}/bark", Mapped: true, AppProperties: []string{"/bark"}},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Pet/properties/kind", Addr: "properties/pet{Dog}/kind"},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/FooProperties/properties/rules/items", Addr: "properties/rules/*", Mapped: true, AppProperties: []string{"/rules/*"}},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/FooProperties/properties/tags/additionalProperties", Addr: "properties/tags/*"},
+		},
+		UnmappedAppProperties: []string{"/extra"},
+	}, cov)
+}
+
+func TestCoverageMerge(t *testing.T) {
+	rootModel := swagger.RootModelInfo{
+		PathRef:   jsonreference.MustCreateRef("foo.json#/paths/~1foos~1{name}"),
+		Operation: "get",
+		Version:   "2023-01-01",
+	}
+	cov1 := &Coverage{
+		APIProperties: []CoverageAPIProperty{
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/name", Addr: "name", Mapped: true, AppProperties: []string{"/name"}},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/location", Addr: "location"},
+		},
+		UnmappedAppProperties: []string{"/loc", "/id"},
+	}
+	cov2 := &Coverage{
+		APIProperties: []CoverageAPIProperty{
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/location", Addr: "location", Mapped: true, AppProperties: []string{"/loc"}},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/type", Addr: "type"},
+		},
+		UnmappedAppProperties: []string{"/id"},
+	}
+	require.Equal(t, &Coverage{
+		APIProperties: []CoverageAPIProperty{
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/location", Addr: "location", Mapped: true, AppProperties: []string{"/loc"}},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/name", Addr: "name", Mapped: true, AppProperties: []string{"/name"}},
+			{RootModel: rootModel, Ref: "foo.json#/definitions/Foo/properties/type", Addr: "type"},
+		},
+		UnmappedAppProperties: []string{"/id"},
+	}, cov1.Merge(cov2))
+	require.Equal(t, cov1, (*Coverage)(nil).Merge(cov1))
+}
+
+func TestCoverageReportMarkdown(t *testing.T) {
+	rootModel := swagger.RootModelInfo{
+		PathRef:   jsonreference.MustCreateRef("foo.json#/paths/~1foos~1{name}"),
+		Operation: "get",
+		Version:   "2023-01-01",
+	}
+	report := CoverageReport{
+		"foo": &Coverage{
+			APIProperties: []CoverageAPIProperty{
+				{RootModel: rootModel, Addr: "location"},
+				{RootModel: rootModel, Addr: "name", Mapped: true, AppProperties: []string{"/name"}},
+			},
+			UnmappedAppProperties: []string{"/id"},
+		},
+	}
+	require.Equal(t, "# Coverage\n"+
+		"\n## foo\n\n"+
+		"1 of 2 API properties are mapped.\n\n"+
+		"### API Properties\n\n"+
+		"| Operation | Property | Mapped | App Properties |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| GET /foos/{name} (2023-01-01) | `location` |  |  |\n"+
+		"| GET /foos/{name} (2023-01-01) | `name` | yes | `/name` |\n"+
+		"\n### Unmapped App Properties\n\n"+
+		"- `/id`\n", report.Markdown())
+}
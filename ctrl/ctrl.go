@@ -10,7 +10,10 @@ import (
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/go-openapi/spec"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
@@ -20,6 +23,7 @@ import (
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 type Option struct {
@@ -28,18 +32,33 @@ type Option struct {
 	ServerOption  mockserver.Option
 	ExecFrom      string
 	ExecTo        string
+	// CacheDir is the directory to persist the expander cache across runs. If empty, the cache is only kept in memory.
+	CacheDir string
+	// CoverageFile is the file to write the coverage report to. If empty, no coverage report is written.
+	CoverageFile string
+	// CoverageFormat is the format of the coverage report, can be "json" (default) or "markdown".
+	CoverageFormat string
+	// OutputMode is the mode of the output, can be "model_map" (default) or "reverse_index".
+	OutputMode string
+	// MinConfidence is the confidence threshold, the mappings whose confidence is lower than it are dropped from the output.
+	MinConfidence float64
 }
 
 type Ctrl struct {
 	ExecSpec      Config
 	ContinueOnErr bool
-	MockServer    mockserver.Server
+	MockServer    *mockserver.Server
 
 	ExecFrom  string
 	ExecTo    string
 	execState ExecutionState
 
 	expanderCache *swagger.ExpanderCache
+
+	CoverageFile   string
+	CoverageFormat string
+	OutputMode     string
+	MinConfidence  float64
 }
 
 type ExecutionState int
@@ -79,48 +98,173 @@ func NewCtrl(opt Option) (*Ctrl, error) {
 		return nil, fmt.Errorf("invalid exec spec: %v", err)
 	}
 
+	switch opt.CoverageFormat {
+	case "", CoverageFormatJSON, CoverageFormatMarkdown:
+	default:
+		return nil, fmt.Errorf("unknown coverage format %q", opt.CoverageFormat)
+	}
+	switch opt.OutputMode {
+	case "", OutputModeModelMap, OutputModeReverseIndex:
+	default:
+		return nil, fmt.Errorf("unknown output mode %q", opt.OutputMode)
+	}
+	if opt.MinConfidence < 0 || opt.MinConfidence > 1 {
+		return nil, fmt.Errorf("min confidence must be in the range of [0, 1], got %v", opt.MinConfidence)
+	}
+
 	srv, err := mockserver.New(opt.ServerOption)
 	if err != nil {
 		return nil, fmt.Errorf("creating mock server: %v", err)
 	}
 
+	expanderCache := swagger.NewExpanderCache()
+	if opt.CacheDir != "" {
+		expanderCache, err = swagger.NewPersistentExpanderCache(opt.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Ctrl{
-		ExecSpec:      execSpec,
-		ContinueOnErr: opt.ContinueOnErr,
-		MockServer:    *srv,
-		ExecFrom:      opt.ExecFrom,
-		ExecTo:        opt.ExecTo,
-		execState:     ExecutionStateBeforeRun,
-		expanderCache: swagger.NewExpanderCache(),
+		ExecSpec:       execSpec,
+		ContinueOnErr:  opt.ContinueOnErr,
+		MockServer:     srv,
+		ExecFrom:       opt.ExecFrom,
+		ExecTo:         opt.ExecTo,
+		execState:      ExecutionStateBeforeRun,
+		expanderCache:  expanderCache,
+		CoverageFile:   opt.CoverageFile,
+		CoverageFormat: opt.CoverageFormat,
+		OutputMode:     opt.OutputMode,
+		MinConfidence:  opt.MinConfidence,
 	}, nil
 }
 
 func validateExecSpec(spec Config) error {
 	validateOverride := func(ovs []Override) error {
 		for _, ov := range ovs {
-			if ov.ResponseBody+ov.ResponseSelectorMerge+ov.ResponseSelectorJSON+ov.ResponsePatchJSON+ov.ResponsePatchMerge == "" && len(ov.ResponseHeader) == 0 && ov.ResponseStatusCode == 0 && ov.RequestModify == nil && ov.ExpanderOption == nil && ov.SynthOption == nil {
+			if ov.ResponseBody+ov.ResponseSelectorMerge+ov.ResponseSelectorJSON+ov.ResponsePatchJSON+ov.ResponsePatchMerge == "" && len(ov.ResponseHeader) == 0 && ov.ResponseStatusCode == 0 && ov.MaxInstances == 0 && len(ov.ResponseSelectorVariant) == 0 && ov.RequestModify == nil && ov.ExpanderOption == nil && ov.SynthOption == nil {
 				return fmt.Errorf("empty override block is not allowed")
 			}
 			if ov.ResponseBody != "" {
-				if ov.ResponseSelectorMerge+ov.ResponseSelectorJSON+ov.ResponsePatchJSON+ov.ResponsePatchMerge != "" || ov.ExpanderOption != nil || ov.SynthOption != nil {
+				if ov.ResponseSelectorMerge+ov.ResponseSelectorJSON+ov.ResponsePatchJSON+ov.ResponsePatchMerge != "" || ov.MaxInstances != 0 || len(ov.ResponseSelectorVariant) != 0 || ov.ExpanderOption != nil || ov.SynthOption != nil {
 					return fmt.Errorf("`response_body` can only be exclusive specified")
 				}
 				continue
 			}
+			for _, v := range ov.ResponseSelectorVariant {
+				addr, err := parseAddrWithoutKey(v)
+				if err != nil {
+					return fmt.Errorf("parsing response selector variant %q: %v", v, err)
+				}
+				if len(*addr) == 0 || (*addr)[len(*addr)-1].Variant == "" {
+					return fmt.Errorf("response selector variant %q must end with a variant (e.g. \"properties/source{AzureBlob}\")", v)
+				}
+			}
+			if ov.MaxInstances < 0 {
+				return fmt.Errorf("`max_instances` must not be negative")
+			}
 			if ov.ResponsePatchJSON != "" && ov.ResponsePatchMerge != "" {
 				return fmt.Errorf("`response_patch_merge` conflicts with `response_patch_json`")
 			}
 			if ov.ResponseSelectorMerge != "" && ov.ResponseSelectorJSON != "" {
 				return fmt.Errorf("`response_selector_merge` conflicts with `response_selector_json`")
 			}
+			if opt := ov.ExpanderOption; opt != nil {
+				if opt.RecursionDepth < 0 {
+					return fmt.Errorf("expander `recursion_depth` must not be negative")
+				}
+				for _, d := range opt.RefRecursionDepth {
+					if d.Depth <= 0 {
+						return fmt.Errorf("expander ref recursion depth of %q: `depth` must be positive", d.Ref)
+					}
+					if !strings.Contains(d.Ref, "#") {
+						return fmt.Errorf("expander ref recursion depth of %q: `ref` must contain a JSON pointer (e.g. \"foo.json#/definitions/Bar\")", d.Ref)
+					}
+				}
+			}
+			if opt := ov.SynthOption; opt != nil {
+				for _, d := range opt.DuplicateElement {
+					if _, err := parseAddrWithoutKey(d.Addr); err != nil {
+						return fmt.Errorf("synthesizer duplicate element of %q: %v", d.Addr, err)
+					}
+				}
+				for _, v := range opt.Value {
+					if _, err := parseAddrWithoutKey(v.Addr); err != nil {
+						return fmt.Errorf("synthesizer value of %q: %v", v.Addr, err)
+					}
+					isGen := v.EnumIndex != nil || v.Format != "" || v.Prefix != ""
+					if v.Value.IsNull() == !isGen {
+						return fmt.Errorf("synthesizer value of %q: exactly one of `value` and the generator attributes (`enum_index`, `format`, `prefix`) has to be specified", v.Addr)
+					}
+					if v.EnumIndex != nil && (v.Format != "" || v.Prefix != "") {
+						return fmt.Errorf("synthesizer value of %q: `enum_index` conflicts with `format` and `prefix`", v.Addr)
+					}
+				}
+				for _, mk := range opt.MapKey {
+					if _, err := parseAddrWithoutKey(mk.Addr); err != nil {
+						return fmt.Errorf("synthesizer map key of %q: %v", mk.Addr, err)
+					}
+					if mk.Format != "" && mk.Unique {
+						return fmt.Errorf("synthesizer map key of %q: `format` conflicts with `unique`", mk.Addr)
+					}
+				}
+				if err := validateSynthAccess(opt.Access); err != nil {
+					return fmt.Errorf("synthesizer: %v", err)
+				}
+				for _, b := range opt.Behavior {
+					if _, err := parseAddrWithoutKey(b.Addr); err != nil {
+						return fmt.Errorf("synthesizer behavior of %q: %v", b.Addr, err)
+					}
+					if err := validateSynthAccess(b.Access); err != nil {
+						return fmt.Errorf("synthesizer behavior of %q: %v", b.Addr, err)
+					}
+				}
+			}
 		}
 		return nil
 	}
 
 	validateVibrate := func(vibrations []Vibration) error {
 		for _, vib := range vibrations {
-			if !vib.Value.Type().IsPrimitiveType() {
-				return fmt.Errorf("vibration's `value` must be a primitive")
+			if (vib.Path == "") == (vib.Addr == "") {
+				return fmt.Errorf("exactly one of vibration's `path` and `addr` must be specified")
+			}
+			var addr *swagger.PropertyAddr
+			if vib.Addr != "" {
+				var err error
+				addr, err = swagger.ParseAddr(vib.Addr)
+				if err != nil {
+					return fmt.Errorf("parsing vibration's `addr` %q: %v", vib.Addr, err)
+				}
+			}
+			switch vib.Op {
+			case "", mockserver.VibrationOpReplace:
+				if vib.Value.IsNull() || !vib.Value.Type().IsPrimitiveType() {
+					return fmt.Errorf("vibration's `value` must be a primitive")
+				}
+			case mockserver.VibrationOpAdd:
+				if vib.Value.IsNull() {
+					return fmt.Errorf("vibration's `value` must be specified for %q", vib.Op)
+				}
+			case mockserver.VibrationOpRemove, mockserver.VibrationOpDuplicate:
+				if !vib.Value.IsNull() {
+					return fmt.Errorf("vibration's `value` can't be specified for %q", vib.Op)
+				}
+				if vib.Op == mockserver.VibrationOpDuplicate {
+					if addr != nil {
+						if len(*addr) == 0 || (*addr)[len(*addr)-1].Type != swagger.PropertyAddrStepTypeIndex {
+							return fmt.Errorf("vibration's `addr` must reference an array element for %q", vib.Op)
+						}
+					} else {
+						idx := strings.LastIndex(vib.Path, "/")
+						if _, err := strconv.Atoi(vib.Path[idx+1:]); err != nil {
+							return fmt.Errorf("vibration's `path` must reference an array element for %q", vib.Op)
+						}
+					}
+				}
+			default:
+				return fmt.Errorf("unknown vibration's `op` %q", vib.Op)
 			}
 		}
 		return nil
@@ -141,6 +285,9 @@ func validateExecSpec(spec Config) error {
 		if err := validateVibrate(exec.Vibrate); err != nil {
 			return fmt.Errorf("%d: %v", i, err)
 		}
+		if err := validateOutput(exec.Output); err != nil {
+			return fmt.Errorf("%d: %v", i, err)
+		}
 		m, ok := execNames[exec.Name]
 		if !ok {
 			m = map[string]bool{}
@@ -163,6 +310,7 @@ func (ctrl *Ctrl) Run(ctx context.Context) error {
 	}
 
 	results := map[string]ModelMap{}
+	coverages := CoverageReport{}
 
 	execTotal := len(ctrl.ExecSpec.Executions)
 	execSkip := 0
@@ -199,7 +347,7 @@ func (ctrl *Ctrl) Run(ctx context.Context) error {
 			continue
 		}
 
-		m, err := ctrl.execute(ctx, execution, i, execTotal)
+		m, cov, err := ctrl.execute(ctx, execution, i, execTotal)
 		if err != nil {
 			execFail++
 			if ctrl.ContinueOnErr {
@@ -209,6 +357,13 @@ func (ctrl *Ctrl) Run(ctx context.Context) error {
 		} else {
 			execSucceed++
 			results[execution.Name] = results[execution.Name].Add(m)
+			coverages[execution.Name] = coverages[execution.Name].Merge(cov)
+		}
+	}
+
+	if ctrl.MinConfidence > 0 {
+		for name, m := range results {
+			results[name] = m.FilterConfidence(ctrl.MinConfidence)
 		}
 	}
 
@@ -217,6 +372,13 @@ func (ctrl *Ctrl) Run(ctx context.Context) error {
 		return err
 	}
 
+	if ctrl.CoverageFile != "" {
+		if err := ctrl.WriteCoverage(ctx, coverages); err != nil {
+			log.Error("Write Coverage", "err", err.Error())
+			return err
+		}
+	}
+
 	// Stop mock server
 	log.Info("Stopping the mock server")
 	if err := ctrl.MockServer.Stop(ctx); err != nil {
@@ -235,7 +397,11 @@ func (ctrl *Ctrl) Run(ctx context.Context) error {
 }
 
 func (ctrl *Ctrl) WriteResult(ctx context.Context, results map[string]ModelMap) error {
-	b, err := json.MarshalIndent(results, "", "  ")
+	var output interface{} = results
+	if ctrl.OutputMode == OutputModeReverseIndex {
+		output = NewReverseIndex(results)
+	}
+	b, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshalling output: %v", err)
 	}
@@ -244,7 +410,19 @@ func (ctrl *Ctrl) WriteResult(ctx context.Context, results map[string]ModelMap)
 	return nil
 }
 
-func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, execTotal int) (ModelMap, error) {
+// WriteCoverage writes the coverage report to the coverage file, in the coverage format.
+func (ctrl *Ctrl) WriteCoverage(ctx context.Context, report CoverageReport) error {
+	b, err := report.Format(ctrl.CoverageFormat)
+	if err != nil {
+		return fmt.Errorf("formatting coverage: %v", err)
+	}
+	if err := os.WriteFile(ctrl.CoverageFile, b, 0644); err != nil {
+		return fmt.Errorf("writing coverage to %s: %v", ctrl.CoverageFile, err)
+	}
+	return nil
+}
+
+func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, execTotal int) (ModelMap, *Coverage, error) {
 	overrides := append([]Override{}, execution.Overrides...)
 	overrides = append(overrides, ctrl.ExecSpec.Overrides...)
 
@@ -260,11 +438,19 @@ func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, exe
 			ResponsePatchJSON:     override.ResponsePatchJSON,
 			ResponseHeader:        override.ResponseHeader,
 			ResponseStatusCode:    override.ResponseStatusCode,
+			MaxInstances:          override.MaxInstances,
 			SynthOption:           &swagger.SynthesizerOption{},
 			ExpanderOption: &swagger.ExpanderOption{
 				Cache: ctrl.expanderCache,
 			},
 		}
+		for _, v := range override.ResponseSelectorVariant {
+			addr, err := swagger.ParseAddr(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			ov.ResponseSelectorVariant = append(ov.ResponseSelectorVariant, *addr)
+		}
 		if ptr := override.RequestModify; ptr != nil {
 			modifier := &swagger.RequestDescriptor{}
 			if ptr.Method != "" {
@@ -282,6 +468,9 @@ func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, exe
 			if opt.UseEnumValue {
 				ov.SynthOption.UseEnumValues = true
 			}
+			if opt.ArrayElementVariants {
+				ov.SynthOption.ArrayElementVariants = true
+			}
 			var del []swagger.SynthDuplicateElement
 			for _, eopt := range opt.DuplicateElement {
 				cnt := 1
@@ -290,7 +479,7 @@ func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, exe
 				}
 				addr, err := swagger.ParseAddr(eopt.Addr)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				del = append(del, swagger.SynthDuplicateElement{
 					Cnt:  cnt,
@@ -298,6 +487,71 @@ func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, exe
 				})
 			}
 			ov.SynthOption.DuplicateElements = del
+
+			var values []swagger.SynthValue
+			for _, vopt := range opt.Value {
+				addr, err := swagger.ParseAddr(vopt.Addr)
+				if err != nil {
+					return nil, nil, err
+				}
+				v := swagger.SynthValue{
+					Addr: *addr,
+				}
+				if vopt.Value.IsNull() {
+					v.Generator = &swagger.SynthValueGenerator{
+						EnumIndex: vopt.EnumIndex,
+						Format:    vopt.Format,
+						Prefix:    vopt.Prefix,
+					}
+				} else {
+					v.Literal, err = ctyToGo(vopt.Value)
+					if err != nil {
+						return nil, nil, fmt.Errorf("converting synthesizer value of %q: %v", vopt.Addr, err)
+					}
+				}
+				values = append(values, v)
+			}
+			ov.SynthOption.Values = values
+
+			var mapKeys []swagger.SynthMapKey
+			for _, mkopt := range opt.MapKey {
+				addr, err := swagger.ParseAddr(mkopt.Addr)
+				if err != nil {
+					return nil, nil, err
+				}
+				mapKeys = append(mapKeys, swagger.SynthMapKey{
+					Addr:   *addr,
+					Keys:   mkopt.Keys,
+					Format: mkopt.Format,
+					Unique: mkopt.Unique,
+					Prefix: mkopt.Prefix,
+				})
+			}
+			ov.SynthOption.MapKeys = mapKeys
+
+			ov.SynthOption.Behavior = swagger.SynthBehavior{
+				NullableAsNull: opt.NullableAsNull,
+				OmitOptional:   opt.OmitOptional,
+				UseDefault:     opt.UseDefault,
+				Access:         swagger.SynthAccess(opt.Access),
+			}
+			var behaviors []swagger.SynthPropertyBehavior
+			for _, bopt := range opt.Behavior {
+				addr, err := swagger.ParseAddr(bopt.Addr)
+				if err != nil {
+					return nil, nil, err
+				}
+				behaviors = append(behaviors, swagger.SynthPropertyBehavior{
+					Addr: *addr,
+					Behavior: swagger.SynthBehavior{
+						NullableAsNull: bopt.NullableAsNull,
+						OmitOptional:   bopt.OmitOptional,
+						UseDefault:     bopt.UseDefault,
+						Access:         swagger.SynthAccess(bopt.Access),
+					},
+				})
+			}
+			ov.SynthOption.PropertyBehaviors = behaviors
 		}
 		if opt := override.ExpanderOption; opt != nil {
 			if opt.EmptyObjAsStr {
@@ -306,6 +560,18 @@ func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, exe
 			if opt.DisableCache {
 				ov.ExpanderOption.Cache = nil
 			}
+			ov.ExpanderOption.RecursionDepth = opt.RecursionDepth
+			if len(opt.RefRecursionDepth) != 0 {
+				depths := map[string]int{}
+				for _, d := range opt.RefRecursionDepth {
+					ref, err := normalizeSpecRef(d.Ref, ctrl.MockServer.Specdir)
+					if err != nil {
+						return nil, nil, fmt.Errorf("expander ref recursion depth: %v", err)
+					}
+					depths[ref] = d.Depth
+				}
+				ov.ExpanderOption.RefRecursionDepths = depths
+			}
 		}
 
 		ovs = append(ovs, ov)
@@ -315,16 +581,24 @@ func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, exe
 
 	appJSON, err := ctrl.runCommand(ctx, execution, execIdx, execTotal, 0, 0)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	m, err := MapSingleAppModel(appJSON, ctrl.MockServer.Records()...)
+	models := ctrl.MockServer.Models()
+	m, err := MapSingleAppModel(appJSON, nil, ctrl.MockServer.Records()...)
 	if err != nil {
 		log.Error("post-execution map models", "error", err)
-		return nil, fmt.Errorf("post-execution %q map models: %v", execution, err)
+		return nil, nil, fmt.Errorf("post-execution %q map models: %v", execution, err)
+	}
+
+	cm, err := MapCompositeAppModel(appJSON, m, ctrl.MockServer.Records()...)
+	if err != nil {
+		log.Error("post-execution map composite models", "error", err)
+		return nil, nil, fmt.Errorf("post-execution %q map composite models: %v", execution, err)
 	}
 
-	mm := m.ToModelMap()
+	mm := m.ToModelMap().Add(cm)
+	mapKeys := swagger.JSONValueMapKeys(ctrl.MockServer.Records()...)
 
 	base := BaseExecInfo{
 		appJSON: appJSON,
@@ -335,24 +609,43 @@ func (ctrl *Ctrl) execute(ctx context.Context, execution Execution, execIdx, exe
 		m, err := ctrl.vibrate(ctx, execution, vibrate, base, execIdx, execTotal, i, len(execution.Vibrate))
 		if err != nil {
 			log.Error("post-execution vibration execution", "error", err)
-			return nil, fmt.Errorf("post-execution vibration execution: %v", err)
+			return nil, nil, fmt.Errorf("post-execution vibration execution: %v", err)
 		}
 		mm = mm.Add(m.ToModelMap())
 	}
 
+	mm, conflicts := mm.GeneralizePointers(appJSON, mapKeys)
+	if len(conflicts) != 0 {
+		log.Warn("Generalized app model properties are mapped to different API properties, their concrete properties are kept as conflicting", "properties", conflicts)
+	}
+
+	var appProps []string
+	for ptr := range flattenJSON(appJSON) {
+		// The conflicting generalized pointers are not in the model map, but their concrete pointers.
+		if gptr := generalizePointer(ptr, appJSON, mapKeys); !slices.Contains(conflicts, gptr) {
+			ptr = gptr
+		}
+		appProps = append(appProps, ptr)
+	}
+	cov, err := NewCoverage(mm, appProps, models, ctrl.MockServer.Specdir)
+	if err != nil {
+		log.Error("post-execution coverage", "error", err)
+		return nil, nil, fmt.Errorf("post-execution %q coverage: %v", execution, err)
+	}
+
 	if err := mm.AddLink(ctrl.MockServer.Idx.Commit, ctrl.MockServer.Specdir); err != nil {
 		log.Error("post-execution model map adding link", "error", err)
-		return nil, fmt.Errorf("post-execution model map adding link: %v", err)
+		return nil, nil, fmt.Errorf("post-execution model map adding link: %v", err)
 	}
 	if err := mm.RelativeLocalLink(ctrl.MockServer.Specdir); err != nil {
 		log.Error("post-execution model map relative local link", "error", err)
-		return nil, fmt.Errorf("post-execution model map relative local link: %v", err)
+		return nil, nil, fmt.Errorf("post-execution model map relative local link: %v", err)
 	}
 
-	return mm, nil
+	return mm, cov, nil
 }
 
-func (ctrl *Ctrl) runCommand(ctx context.Context, execution Execution, execIdx, execTotal int, vibrateIdx, vibrateTotal int) (map[string]interface{}, error) {
+func (ctrl *Ctrl) runCommand(ctx context.Context, execution Execution, execIdx, execTotal int, vibrateIdx, vibrateTotal int) (interface{}, error) {
 	env := os.Environ()
 	for k, v := range execution.Env {
 		env = append(env, k+"="+v)
@@ -389,17 +682,30 @@ func (ctrl *Ctrl) runCommand(ctx context.Context, execution Execution, execIdx,
 
 	log.Debug("execution result", "stdout", stdout.String())
 
-	var appJSON map[string]interface{}
-	if err := json.Unmarshal(stdout.Bytes(), &appJSON); err != nil {
-		log.Error(fmt.Sprintf("post-execution %q%s unmarshal failure", execution, vibrateMsg), "error", err, "stdout", stdout.String())
-		return nil, fmt.Errorf("post-execution %q%s unmarshal: %v", execution, vibrateMsg, err)
+	appJSON, err := parseOutput(stdout.Bytes(), execution.Output)
+	if err != nil {
+		log.Error(fmt.Sprintf("post-execution %q%s parsing output failure", execution, vibrateMsg), "error", err, "stdout", stdout.String())
+		return nil, fmt.Errorf("post-execution %q%s parsing output: %v", execution, vibrateMsg, err)
 	}
 
 	return appJSON, nil
 }
 
+// ctyToGo converts a cty value to its Go representation as is unmarshalled from JSON.
+func ctyToGo(v cty.Value) (interface{}, error) {
+	b, err := ctyjson.Marshal(v, v.Type())
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 type BaseExecInfo struct {
-	appJSON map[string]interface{}
+	appJSON interface{}
 	seq     []mockserver.MonoModelDesc
 }
 
@@ -408,17 +714,34 @@ func (ctrl *Ctrl) vibrate(ctx context.Context, execution Execution, vibration Vi
 	var value interface{}
 	vv := vibration.Value
 	switch {
+	case vv.IsNull():
 	case vv.Type().Equals(cty.Number):
 		value, _ = vv.AsBigFloat().Float64()
 	case vv.Type().Equals(cty.Bool):
 		value = vv.True()
 	case vv.Type().Equals(cty.String):
 		value = vv.AsString()
+	default:
+		var err error
+		value, err = ctyToGo(vv)
+		if err != nil {
+			return nil, fmt.Errorf("converting vibration value: %v", err)
+		}
+	}
+	var addr *swagger.PropertyAddr
+	if vibration.Addr != "" {
+		var err error
+		addr, err = swagger.ParseAddr(vibration.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing vibration address %q: %v", vibration.Addr, err)
+		}
 	}
 	ctrl.MockServer.InitVibration(
 		&mockserver.Vibration{
 			PathPattern: *regexp.MustCompile(vibration.PathPattern),
 			Path:        vibration.Path,
+			Addr:        addr,
+			Op:          vibration.Op,
 			Value:       value,
 		},
 	)
@@ -442,45 +765,65 @@ func (ctrl *Ctrl) vibrate(ctx context.Context, execution Execution, vibration Vi
 		return nil, nil
 	}
 	if len(l1)+len(l2) != 0 {
-		msg := "Vibration causes "
-		if len(l1) != 0 {
-			msg += fmt.Sprintf("properties only in base model: %v", l1)
-		}
-		if len(l2) != 0 {
-			if len(l1) != 0 {
-				msg += " and "
-			}
-			msg += fmt.Sprintf("properties only in vibration model: %v", l2)
-		}
-		log.Error("Vibration causes property set mismatch", "base only props", l1, "vibration only props", l2)
-		return nil, fmt.Errorf(msg)
+		log.Info("Vibration causes property set change, which are mapped conditionally", "base only props", l1, "vibration only props", l2)
 	}
-	if len(ldiff) != 1 {
-		log.Warn("Vibration causes more than one diff properties", "properties", ldiff)
-		// TODO: conditionally accept this
-		return nil, nil
+	if len(ldiff) > 1 {
+		log.Info("Vibration causes more than one diff properties, which are all mapped", "properties", ldiff)
 	}
-	appPropAddr := ldiff[0]
 
 	vibrationRecord := ctrl.MockServer.VibrationRecord()
 	if vibrationRecord == nil {
 		log.Error("vibration record is unexpected nil")
 		return nil, fmt.Errorf("vibration record is unexpected nil")
 	}
-	fltAPIModel := swagger.FlattenJSONValueObjectByAddr((*vibrationRecord).(swagger.JSONObject))
-	for k, v := range fltAPIModel {
-		addr, err := swagger.ParseAddr(k)
-		if err != nil {
-			return nil, err
-		}
-		ptr, err := addr.ToPointer()
-		if err != nil {
-			return nil, err
-		}
-		if ptr.String() != vibration.Path {
-			continue
+	vibrationPath := ctrl.MockServer.VibrationPath()
+	pos, err := swagger.JSONValuePosByPointer(*vibrationRecord, vibrationPath)
+	if err != nil {
+		return nil, fmt.Errorf("finding the vibrated property in the vibration model: %v", err)
+	}
+	if pos == nil {
+		return nil, fmt.Errorf("the vibrated property %s is not defined in the vibration model", vibrationPath)
+	}
+	presence := vibration.Op != "" && vibration.Op != mockserver.VibrationOpReplace
+	return mapVibration(fltAppJSON, fltVibrateAppJSON, pos, presence), nil
+}
+
+// parseAddrWithoutKey parses the property address, which is not allowed to have the index (or key) qualifiers, as they are only
+// meaningful to address a concrete JSON value (i.e. in the vibration).
+func parseAddrWithoutKey(input string) (*swagger.PropertyAddr, error) {
+	addr, err := swagger.ParseAddr(input)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range *addr {
+		if step.Key != "" {
+			return nil, fmt.Errorf("the index (or key) qualifier %q is only allowed in the vibration's `addr`", step.Key)
 		}
-		return SingleModelMap{appPropAddr: v.JSONValuePos()}, nil
 	}
-	return nil, fmt.Errorf("failed to find a leaf property address %s in the vibration model", vibration.Path)
+	return addr, nil
+}
+
+func validateSynthAccess(access string) error {
+	switch swagger.SynthAccess(access) {
+	case swagger.SynthAccessAll, swagger.SynthAccessReadOnly, swagger.SynthAccessWritable:
+		return nil
+	default:
+		return fmt.Errorf("invalid `access` %q, must be one of %q and %q", access, swagger.SynthAccessReadOnly, swagger.SynthAccessWritable)
+	}
+}
+
+// normalizeSpecRef normalizes the ref that is relative to the specdir into an absolute ref.
+func normalizeSpecRef(ref, specdir string) (string, error) {
+	path, ptr, ok := strings.Cut(ref, "#")
+	if !ok {
+		return "", fmt.Errorf("ref %q has no JSON pointer", ref)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(specdir, path)
+	}
+	nref, err := spec.NewRef(path + "#" + ptr)
+	if err != nil {
+		return "", fmt.Errorf("invalid ref %q: %v", ref, err)
+	}
+	return nref.String(), nil
 }
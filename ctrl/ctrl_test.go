@@ -0,0 +1,77 @@
+package ctrl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestValidateExecSpecAddrKey(t *testing.T) {
+	withSynth := func(opt *SynthOption) Config {
+		return Config{
+			Overrides: []Override{{PathPattern: ".*", SynthOption: opt}},
+		}
+	}
+	cases := []struct {
+		name string
+		spec Config
+		err  bool
+	}{
+		{
+			name: "synth value without key",
+			spec: withSynth(&SynthOption{Value: []SynthValue{{Addr: "properties/rules/*/name", Value: cty.StringVal("a")}}}),
+		},
+		{
+			name: "synth value with key",
+			spec: withSynth(&SynthOption{Value: []SynthValue{{Addr: "properties/rules/*[1]/name", Value: cty.StringVal("a")}}}),
+			err:  true,
+		},
+		{
+			name: "synth map key with key",
+			spec: withSynth(&SynthOption{MapKey: []SynthMapKey{{Addr: "tags/*[foo]", Keys: []string{"a"}}}}),
+			err:  true,
+		},
+		{
+			name: "synth duplicate element with key",
+			spec: withSynth(&SynthOption{DuplicateElement: []DuplicateElement{{Addr: "properties/rules/*[0]"}}}),
+			err:  true,
+		},
+		{
+			name: "synth behavior with key",
+			spec: withSynth(&SynthOption{Behavior: []SynthBehavior{{Addr: "properties/rules/*[0]"}}}),
+			err:  true,
+		},
+		{
+			name: "response selector variant with key",
+			spec: Config{
+				Overrides: []Override{{PathPattern: ".*", ResponseSelectorVariant: []string{"properties/pets/*[0]{Dog}"}}},
+			},
+			err: true,
+		},
+		{
+			name: "vibration with key",
+			spec: Config{
+				Executions: []Execution{
+					{
+						Name: "foo",
+						Type: "basic",
+						Vibrate: []Vibration{
+							{PathPattern: ".*", Addr: "properties/rules/*[1]", Value: cty.StringVal("a")},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExecSpec(tt.spec)
+			if tt.err {
+				require.ErrorContains(t, err, "qualifier")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,23 +21,172 @@ import (
 // encountered a circular reference during its expansion, the value of the map is nil.
 type SingleModelMap map[string]*swagger.JSONValuePos
 
-func MapSingleAppModel(appModel map[string]interface{}, apiModels ...swagger.JSONValue) (SingleModelMap, error) {
+type MapOption struct {
+	// Matchers are used to match the application values that are not exactly equal to any API model value.
+	// Defaults to DefaultValueMatchers.
+	Matchers []ValueMatcher
+}
+
+// MapSingleAppModel maps the leaf properties of the application model to the ones of the API models, by matching their values.
+// The values are firstly matched exactly, then by the values derived by the matchers. The latter mapping has the
+// transform annotated in the JSONValuePos. Each mapping is annotated with its provenance and confidence.
+func MapSingleAppModel(appModel interface{}, opt *MapOption, apiModels ...swagger.JSONValue) (SingleModelMap, error) {
+	if opt == nil {
+		opt = &MapOption{}
+	}
+	matchers := opt.Matchers
+	if matchers == nil {
+		matchers = DefaultValueMatchers
+	}
+
 	apiValueMap, err := swagger.JSONValueValueMap(apiModels...)
 	if err != nil {
 		return nil, fmt.Errorf("building value map for API models: %v", err)
 	}
+	derivedValueMap := deriveValueMap(apiValueMap, matchers)
+
 	m := map[string]*swagger.JSONValuePos{}
 	appValueMap := jsonValueMap(appModel)
 	for val, appAddr := range appValueMap {
-		apiAddr, ok := apiValueMap[val]
+		if apiAddr, ok := apiValueMap[val]; ok {
+			m[appAddr] = withProvenance(apiAddr, swagger.ProvenanceValueMatch, valueConfidence(val))
+			continue
+		}
+		if apiAddr, ok := derivedValueMap[val]; ok {
+			m[appAddr] = withProvenance(apiAddr, swagger.ProvenanceTransform, transformConfidenceFactor*valueConfidence(val))
+		}
+	}
+	return m, nil
+}
+
+// deriveValueMap builds a value map from the values derived from the API value map by the matchers, whose JSONValuePos
+// are copies of the original ones with the transform annotated.
+// The derived values that are ambiguous (i.e. derived from different API properties), or equal to the original API value, are not included.
+func deriveValueMap(apiValueMap map[string]*swagger.JSONValuePos, matchers []ValueMatcher) map[string]*swagger.JSONValuePos {
+	out := map[string]*swagger.JSONValuePos{}
+	dupm := map[string]bool{}
+
+	tryStore := func(k string, v *swagger.JSONValuePos) {
+		if dupm[k] {
+			return
+		}
+		if ov, ok := out[k]; ok {
+			if ov.String() != v.String() {
+				delete(out, k)
+				dupm[k] = true
+			}
+			return
+		}
+		out[k] = v
+	}
+
+	for val, pos := range apiValueMap {
+		for _, matcher := range matchers {
+			for _, dv := range matcher.Derive(val) {
+				if dv.Value == val {
+					continue
+				}
+				npos := *pos
+				npos.Transform = dv.Transform
+				tryStore(dv.Value, &npos)
+			}
+		}
+	}
+	return out
+}
+
+// MapCompositeAppModel maps the string leaf properties of the application model, that are not mapped yet, to the API
+// properties whose values compose it. E.g. a resource ID or a connection string is usually built from several API values.
+// An application value is regarded as composite when it contains at least two (unambiguous) API string values as whole
+// tokens (i.e. delimited by non-alphanumeric characters). The resulting JSONValuePos(s) are annotated with their
+// 1-based order in the application value.
+func MapCompositeAppModel(appModel interface{}, mapped SingleModelMap, apiModels ...swagger.JSONValue) (ModelMap, error) {
+	apiValueMap, err := swagger.JSONValueValueMap(apiModels...)
+	if err != nil {
+		return nil, fmt.Errorf("building value map for API models: %v", err)
+	}
+
+	// Only the (defined) string values are considered as the tokens, with the longer ones tried first.
+	var tokens []string
+	for val, pos := range apiValueMap {
+		if pos == nil || val == "TRUE" || val == "FALSE" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(val, 64); err == nil {
+			continue
+		}
+		tokens = append(tokens, val)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if len(tokens[i]) != len(tokens[j]) {
+			return len(tokens[i]) > len(tokens[j])
+		}
+		return tokens[i] < tokens[j]
+	})
+
+	m := ModelMap{}
+	for appAddr, val := range flattenJSON(appModel) {
+		if _, ok := mapped[appAddr]; ok {
+			continue
+		}
+		str, ok := val.(string)
 		if !ok {
 			continue
 		}
-		m[appAddr] = apiAddr
+		found := findTokens(str, tokens)
+		if len(found) < 2 {
+			continue
+		}
+		var poses []*swagger.JSONValuePos
+		for i, tk := range found {
+			pos := withProvenance(apiValueMap[tk], swagger.ProvenanceComposite, compositeConfidenceFactor*valueConfidence(tk))
+			pos.Composite = i + 1
+			poses = append(poses, pos)
+		}
+		m[appAddr] = poses
 	}
 	return m, nil
 }
 
+// findTokens finds the tokens that appear in the string as whole tokens, without overlapping with each other.
+// The tokens are tried in order. The found ones are returned in the order of their (first) appearance in the string.
+func findTokens(str string, tokens []string) []string {
+	claimed := make([]bool, len(str))
+	isBoundary := func(i int) bool {
+		if i < 0 || i >= len(str) {
+			return true
+		}
+		c := str[i]
+		return !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9')
+	}
+	offsets := map[string]int{}
+	for _, tk := range tokens {
+		for start := 0; start+len(tk) <= len(str); {
+			idx := strings.Index(str[start:], tk)
+			if idx == -1 {
+				break
+			}
+			begin, end := start+idx, start+idx+len(tk)
+			start = begin + 1
+			if !isBoundary(begin-1) || !isBoundary(end) || slices.Contains(claimed[begin:end], true) {
+				continue
+			}
+			for i := begin; i < end; i++ {
+				claimed[i] = true
+			}
+			if _, ok := offsets[tk]; !ok {
+				offsets[tk] = begin
+			}
+			start = end
+		}
+	}
+	found := maps.Keys(offsets)
+	sort.Slice(found, func(i, j int) bool {
+		return offsets[found[i]] < offsets[found[j]]
+	})
+	return found
+}
+
 func (smm SingleModelMap) ToModelMap() ModelMap {
 	m := ModelMap{}
 	for k, v := range smm {
@@ -49,12 +199,21 @@ func (smm SingleModelMap) ToModelMap() ModelMap {
 // This is resulted from merging multiple SingleModelMap(s).
 type ModelMap map[string][]*swagger.JSONValuePos
 
+// Add merges the other ModelMap into this one. When the same API property is mapped the same way by both, the one with the higher confidence wins.
 func (mm ModelMap) Add(omm ModelMap) ModelMap {
 	tmpM := map[string]map[string]*swagger.JSONValuePos{}
+	store := func(m map[string]*swagger.JSONValuePos, pos *swagger.JSONValuePos) {
+		if opos, ok := m[posKey(pos)]; ok && opos.Confidence > pos.Confidence {
+			return
+		}
+		m[posKey(pos)] = pos
+	}
 	for k, poses := range mm {
+		m := map[string]*swagger.JSONValuePos{}
 		for _, pos := range poses {
-			tmpM[k] = map[string]*swagger.JSONValuePos{pos.String(): pos}
+			store(m, pos)
 		}
+		tmpM[k] = m
 	}
 	for k, poses := range omm {
 		for _, pos := range poses {
@@ -63,7 +222,7 @@ func (mm ModelMap) Add(omm ModelMap) ModelMap {
 				m = map[string]*swagger.JSONValuePos{}
 				tmpM[k] = m
 			}
-			m[pos.String()] = pos
+			store(m, pos)
 		}
 	}
 
@@ -74,13 +233,120 @@ func (mm ModelMap) Add(omm ModelMap) ModelMap {
 			l = append(l, v)
 		}
 		sort.Slice(l, func(i, j int) bool {
-			return l[i].Addr.String() < l[j].Addr.String()
+			if l[i].Composite != l[j].Composite {
+				return l[i].Composite < l[j].Composite
+			}
+			if l[i].Addr.String() != l[j].Addr.String() {
+				return l[i].Addr.String() < l[j].Addr.String()
+			}
+			return l[i].Transform < l[j].Transform
 		})
 		result[k] = l
 	}
 	return result
 }
 
+// posKey identifies a JSONValuePos together with how it is mapped.
+func posKey(pos *swagger.JSONValuePos) string {
+	return pos.String() + "|" + pos.Transform + "|" + strconv.Itoa(pos.Composite) + "|" + pos.Conditional + "|" + strconv.FormatBool(pos.Presence)
+}
+
+// GeneralizePointers replaces the array indices (of the app model) and the map keys (that are generated by the synthesizer) in the
+// app model property pointers with "*", and merges the resulting duplicates.
+// The generalized pointers, whose concrete pointers are mapped to different API properties (e.g. "/0/p" and "/1/p" are mapped to
+// different variants of a polymorphic array element), are conflicting. Their concrete pointers are kept as is, with the positions
+// marked as Conflicting. The conflicting generalized pointers are also returned.
+func (mm ModelMap) GeneralizePointers(appModel interface{}, keys map[string]bool) (ModelMap, []string) {
+	gks := map[string]string{}
+	apiProps := map[string]string{}
+	conflicts := map[string]bool{}
+	for _, k := range sortedKeys(mm) {
+		gk := generalizePointer(k, appModel, keys)
+		gks[k] = gk
+		var l []string
+		for _, pos := range mm[k] {
+			l = append(l, pos.String())
+		}
+		sort.Strings(l)
+		props := strings.Join(l, ",")
+		if v, ok := apiProps[gk]; ok && v != props {
+			conflicts[gk] = true
+		}
+		apiProps[gk] = props
+	}
+
+	result := ModelMap{}
+	for _, k := range sortedKeys(mm) {
+		gk := gks[k]
+		if !conflicts[gk] {
+			result = result.Add(ModelMap{gk: mm[k]})
+			continue
+		}
+		var poses []*swagger.JSONValuePos
+		for _, pos := range mm[k] {
+			npos := *pos
+			npos.Conflicting = true
+			poses = append(poses, &npos)
+		}
+		result = result.Add(ModelMap{k: poses})
+	}
+	return result, sortedKeys(conflicts)
+}
+
+// generalizePointer replaces the tokens of the JSON pointer that are array indices of the app model, or keys of a map of the app model, with "*".
+// An object of the app model is regarded as a map only when all its keys exist in the (synthesized map) keys, so that the attribute names that happen
+// to equal to some map key are kept as is.
+func generalizePointer(ptr string, appModel interface{}, keys map[string]bool) string {
+	p, err := jsonpointer.New(ptr)
+	if err != nil {
+		return ptr
+	}
+	tks := p.DecodedTokens()
+	if len(tks) == 0 {
+		return ptr
+	}
+	node := appModel
+	etks := make([]string, 0, len(tks))
+	for _, tk := range tks {
+		switch n := node.(type) {
+		case []interface{}:
+			node = nil
+			if idx, err := strconv.Atoi(tk); err == nil && idx >= 0 && idx < len(n) {
+				node = n[idx]
+			}
+			tk = "*"
+		case map[string]interface{}:
+			node = n[tk]
+			if isMapObject(n, keys) {
+				tk = "*"
+			}
+		default:
+			node = nil
+		}
+		etks = append(etks, jsonpointer.Escape(tk))
+	}
+	return "/" + strings.Join(etks, "/")
+}
+
+// isMapObject tells whether the object is a map, whose keys are all synthesized map keys.
+func isMapObject(obj map[string]interface{}, keys map[string]bool) bool {
+	if len(obj) == 0 || len(keys) == 0 {
+		return false
+	}
+	for k := range obj {
+		if !keys[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := maps.Keys(m)
+	sort.Strings(keys)
+	return keys
+}
+
 // AddLink adds the LinkLocal and LinkGithuhub for each value (*swagger.JSONValuePos) of the ModelMap.
 func (m ModelMap) AddLink(commit, specdir string) error {
 	pm := map[string][]jsonpointer.Pointer{}
@@ -157,8 +423,8 @@ func (m ModelMap) RelativeLocalLink(specdir string) error {
 	return nil
 }
 
-// jsonValueMap flattens a JSON object to a single level k-v map that mapps the jsonpointer to each property to the strings representation of its value, and reverse the keys and values to be a value map.
-func jsonValueMap(m map[string]interface{}) map[string]string {
+// jsonValueMap flattens a JSON value to a single level k-v map that mapps the jsonpointer to each property to the strings representation of its value, and reverse the keys and values to be a value map.
+func jsonValueMap(v interface{}) map[string]string {
 	out := map[string]string{}
 	dupm := map[string]bool{}
 
@@ -174,8 +440,7 @@ func jsonValueMap(m map[string]interface{}) map[string]string {
 		out[k] = v
 	}
 
-	m = flattenJSON(m)
-	for k, val := range m {
+	for k, val := range flattenJSON(v) {
 		switch val := val.(type) {
 		case float64:
 			tryStore(strconv.FormatFloat(val, 'g', -1, 64), k)
@@ -192,21 +457,25 @@ func jsonValueMap(m map[string]interface{}) map[string]string {
 	return out
 }
 
-// flattenJSON flattens a JSON object to a single level k-v map that mapps the jsonpointer to each property's value,
-func flattenJSON(m map[string]interface{}) map[string]interface{} {
+// flattenJSON flattens a JSON value to a single level k-v map that mapps the jsonpointer to each property's value.
+// A primitive JSON value is mapped from the root pointer (i.e. "").
+func flattenJSON(v interface{}) map[string]interface{} {
 	out := map[string]interface{}{}
 	fn := func(val interface{}, tks []string) {
 		etks := make([]string, 0, len(tks))
 		for _, tk := range tks {
 			etks = append(etks, jsonpointer.Escape(tk))
 		}
-		ptr, _ := jsonpointer.New("/" + strings.Join(etks, "/"))
+		var ptr string
+		if len(etks) != 0 {
+			ptr = "/" + strings.Join(etks, "/")
+		}
 		switch val := val.(type) {
 		case float64, string, bool:
-			out[ptr.String()] = val
+			out[ptr] = val
 		}
 	}
-	walkJSON(m, []string{}, fn)
+	walkJSON(v, []string{}, fn)
 	return out
 }
 
@@ -230,6 +499,37 @@ func walkJSON(node interface{}, ptks []string, fn func(node interface{}, ptrtks
 	return
 }
 
+// mapVibration maps the application properties that are changed by a vibration (i.e. the base and the vibrated flattened application models
+// differ) to the vibrated API property. The properties that only exist in one of the models are mapped conditionally.
+// If presence is true, the vibration changes the presence (or the array length) of the API property, rather than its value. In which case the
+// mappings are annotated as presence mappings.
+func mapVibration(base, vibrated map[string]interface{}, pos *swagger.JSONValuePos, presence bool) SingleModelMap {
+	l1, l2, ldiff := compareFlattendJSON(base, vibrated)
+	if len(l1)+len(l2)+len(ldiff) == 0 {
+		return nil
+	}
+	pos = withProvenance(pos, swagger.ProvenanceVibration, vibrationConfidence)
+	if presence {
+		pos.Presence = true
+	}
+	m := SingleModelMap{}
+	for _, appPropAddr := range ldiff {
+		m[appPropAddr] = pos
+	}
+	for conditional, appPropAddrs := range map[string][]string{
+		swagger.ConditionalDisappear: l1,
+		swagger.ConditionalAppear:    l2,
+	} {
+		for _, appPropAddr := range appPropAddrs {
+			npos := *pos
+			npos.Conditional = conditional
+			npos.Confidence = conditionalVibrationConfidence
+			m[appPropAddr] = &npos
+		}
+	}
+	return m
+}
+
 // compareFlattendJSON compares two flattend JSON object and returns:
 // - Properties only exist in the 1st object
 // - Properties only exist in the 2nd object
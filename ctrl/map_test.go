@@ -3,13 +3,14 @@ package ctrl
 import (
 	"testing"
 
+	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger"
 	"github.com/stretchr/testify/require"
 )
 
 func TestJSONValueMap(t *testing.T) {
 	cases := []struct {
 		name   string
-		input  map[string]interface{}
+		input  interface{}
 		expect map[string]string
 	}{
 		{
@@ -32,6 +33,24 @@ func TestJSONValueMap(t *testing.T) {
 				"TRUE": "/bool",
 			},
 		},
+		{
+			name: "array",
+			input: []interface{}{
+				map[string]interface{}{"p1": "foo"},
+				"bar",
+			},
+			expect: map[string]string{
+				"foo": "/0/p1",
+				"bar": "/1",
+			},
+		},
+		{
+			name:  "primitive",
+			input: "foo",
+			expect: map[string]string{
+				"foo": "",
+			},
+		},
 	}
 
 	for _, tt := range cases {
@@ -41,3 +60,427 @@ func TestJSONValueMap(t *testing.T) {
 		})
 	}
 }
+
+func TestModelMapGeneralizePointersMapKeys(t *testing.T) {
+	pos1 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("tags/*")}
+	pos2 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("props/*/p1")}
+	pos3 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("name")}
+	input := ModelMap{
+		"/tags/KEY":       {pos1},
+		"/tags/KEY1":      {pos1},
+		"/props/KEY~1a/p": {pos2},
+		"/name":           {pos3},
+	}
+	appModel := map[string]interface{}{
+		"tags": map[string]interface{}{
+			"KEY":  "a",
+			"KEY1": "a",
+		},
+		"props": map[string]interface{}{
+			"KEY/a": map[string]interface{}{"p": "b"},
+		},
+		"name": "c",
+	}
+	keys := map[string]bool{
+		"KEY":   true,
+		"KEY1":  true,
+		"KEY/a": true,
+	}
+	out, conflicts := input.GeneralizePointers(appModel, keys)
+	require.Equal(t, ModelMap{
+		"/tags/*":    {pos1},
+		"/props/*/p": {pos2},
+		"/name":      {pos3},
+	}, out)
+	require.Empty(t, conflicts)
+}
+
+func TestModelMapAdd(t *testing.T) {
+	pos1 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("name")}
+	pos2 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/name")}
+	pos3 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("location")}
+	mm := ModelMap{
+		"/name": {pos1, pos2},
+	}
+	require.Equal(t, ModelMap{
+		"/name":     {pos1, pos2},
+		"/location": {pos3},
+	}, mm.Add(ModelMap{"/name": {pos2}, "/location": {pos3}}))
+}
+
+func TestValueMatchers(t *testing.T) {
+	cases := []struct {
+		name    string
+		matcher ValueMatcherFunc
+		input   string
+		expect  []DerivedValue
+	}{
+		{
+			name:    "case",
+			matcher: matchCase,
+			input:   "ReadWrite",
+			expect: []DerivedValue{
+				{Value: "readwrite", Transform: "lower"},
+				{Value: "READWRITE", Transform: "upper"},
+				{Value: "read_write", Transform: "snake"},
+			},
+		},
+		{
+			name:    "armid",
+			matcher: matchARMID,
+			input:   "/subscriptions/sub1/resourceGroups/rg1",
+			expect: []DerivedValue{
+				{Value: "sub1", Transform: "armid.segment(subscriptions)"},
+				{Value: "rg1", Transform: "armid.segment(resourceGroups)"},
+			},
+		},
+		{
+			name:    "tenant level armid",
+			matcher: matchARMID,
+			input:   "/providers/Microsoft.Foo/foos/foo1",
+			expect: []DerivedValue{
+				{Value: "Microsoft.Foo", Transform: "armid.segment(providers)"},
+				{Value: "foo1", Transform: "armid.segment(foos)"},
+			},
+		},
+		{
+			name:    "management group armid",
+			matcher: matchARMID,
+			input:   "/providers/Microsoft.Management/managementGroups/mg1",
+			expect: []DerivedValue{
+				{Value: "Microsoft.Management", Transform: "armid.segment(providers)"},
+				{Value: "mg1", Transform: "armid.segment(managementGroups)"},
+			},
+		},
+		{
+			name:    "provider only",
+			matcher: matchARMID,
+			input:   "/providers/Microsoft.Foo",
+		},
+		{
+			name:    "not an armid",
+			matcher: matchARMID,
+			input:   "/foo/bar",
+		},
+		{
+			name:    "split",
+			matcher: matchSplit,
+			input:   "a, b",
+			expect: []DerivedValue{
+				{Value: "a", Transform: `split(",")`},
+				{Value: "b", Transform: `split(",")`},
+			},
+		},
+		{
+			name:    "duration",
+			matcher: matchDuration,
+			input:   "PT1H30M",
+			expect: []DerivedValue{
+				{Value: "5400", Transform: "duration.seconds"},
+				{Value: "90", Transform: "duration.minutes"},
+			},
+		},
+		{
+			name:    "duration in seconds",
+			matcher: matchDuration,
+			input:   "P1DT1H1M1S",
+			expect: []DerivedValue{
+				{Value: "90061", Transform: "duration.seconds"},
+			},
+		},
+		{
+			name:    "base64",
+			matcher: matchBase64,
+			input:   "aGVsbG8=",
+			expect: []DerivedValue{
+				{Value: "hello", Transform: "base64.decode"},
+			},
+		},
+		{
+			name:    "base64url",
+			matcher: matchBase64,
+			input:   "Pz8_Pz4-",
+			expect: []DerivedValue{
+				{Value: "????>>", Transform: "base64.decode"},
+			},
+		},
+		{
+			name:    "raw base64url",
+			matcher: matchBase64,
+			input:   "aGVsbG8_Pw",
+			expect: []DerivedValue{
+				{Value: "hello??", Transform: "base64.decode"},
+			},
+		},
+		{
+			name:    "not base64",
+			matcher: matchBase64,
+			input:   "hello",
+		},
+		{
+			name:    "not printable",
+			matcher: matchBase64,
+			input:   "AAE=",
+		},
+		{
+			name:    "too short",
+			matcher: matchBase64,
+			input:   "Zm9v",
+		},
+		{
+			name:    "not canonical",
+			matcher: matchBase64,
+			input:   "aGVsbG9=",
+		},
+		{
+			name:    "time",
+			matcher: matchTime,
+			input:   "2023-01-02T11:04:05+08:00",
+			expect: []DerivedValue{
+				{Value: "2023-01-02T03:04:05Z", Transform: "time.format(2006-01-02T15:04:05Z07:00)"},
+				{Value: "2023-01-02T03:04:05Z", Transform: "time.format(2006-01-02T15:04:05Z)"},
+				{Value: "2023-01-02 03:04:05", Transform: "time.format(2006-01-02 15:04:05)"},
+				{Value: "2023-01-02", Transform: "time.format(2006-01-02)"},
+				{Value: "Mon, 02 Jan 2023 03:04:05 UTC", Transform: "time.format(Mon, 02 Jan 2006 15:04:05 MST)"},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expect, tt.matcher.Derive(tt.input))
+		})
+	}
+}
+
+func TestDeriveValueMap(t *testing.T) {
+	pos1 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("id")}
+	pos2 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/state")}
+	pos3 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/parentId")}
+	input := map[string]*swagger.JSONValuePos{
+		"/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Foo/foos/foo1": pos1,
+		"Enabled":                                pos2,
+		"/subscriptions/sub1/resourceGroups/rg2": pos3,
+	}
+	withTransform := func(pos *swagger.JSONValuePos, transform string) *swagger.JSONValuePos {
+		npos := *pos
+		npos.Transform = transform
+		return &npos
+	}
+	out := deriveValueMap(input, DefaultValueMatchers)
+	for k, v := range map[string]*swagger.JSONValuePos{
+		"rg1":           withTransform(pos1, "armid.segment(resourceGroups)"),
+		"Microsoft.Foo": withTransform(pos1, "armid.segment(providers)"),
+		"foo1":          withTransform(pos1, "armid.segment(foos)"),
+		"rg2":           withTransform(pos3, "armid.segment(resourceGroups)"),
+		"enabled":       withTransform(pos2, "lower"),
+		"ENABLED":       withTransform(pos2, "upper"),
+	} {
+		require.Equal(t, v, out[k], k)
+	}
+	// ambiguous
+	require.NotContains(t, out, "sub1")
+	// same as the original value
+	require.NotContains(t, out, "Enabled")
+}
+
+func TestFindTokens(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		tokens []string
+		expect []string
+	}{
+		{
+			name:   "resource id",
+			input:  "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/b/providers/Microsoft.Foo/foos/c",
+			tokens: []string{"c", "b"},
+			expect: []string{"b", "c"},
+		},
+		{
+			name:   "connection string",
+			input:  "Endpoint=https://d.com;Key=e",
+			tokens: []string{"https://d.com", "e", "d"},
+			expect: []string{"https://d.com", "e"},
+		},
+		{
+			name:   "not a whole token",
+			input:  "abc-b",
+			tokens: []string{"ab", "b"},
+			expect: []string{"b"},
+		},
+		{
+			name:   "repeated token",
+			input:  "b/c/b",
+			tokens: []string{"b", "c"},
+			expect: []string{"b", "c"},
+		},
+		{
+			name:   "no token",
+			input:  "foo",
+			tokens: []string{"b"},
+			expect: []string{},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expect, findTokens(tt.input, tt.tokens))
+		})
+	}
+}
+
+func TestModelMapGeneralizePointers(t *testing.T) {
+	pos1 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/ipRules/*/value")}
+	pos2 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/rules/*{A}/a")}
+	pos3 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/rules/*{B}/a")}
+	pos4 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("tags/*")}
+	appModel := map[string]interface{}{
+		"ip_rules": []interface{}{
+			map[string]interface{}{"value": "b"},
+			map[string]interface{}{"value": "c"},
+		},
+		"rules": []interface{}{
+			map[string]interface{}{"a": "d"},
+			map[string]interface{}{"a": "e"},
+		},
+		"tags": map[string]interface{}{
+			"KEY": "f",
+		},
+		"0": "g",
+	}
+	input := ModelMap{
+		"/ip_rules/0/value": {pos1},
+		"/ip_rules/1/value": {pos1},
+		"/rules/0/a":        {pos2},
+		"/rules/1/a":        {pos3},
+		"/tags/KEY":         {pos4},
+	}
+	out, conflicts := input.GeneralizePointers(appModel, map[string]bool{"KEY": true})
+	conflicting := func(pos *swagger.JSONValuePos) *swagger.JSONValuePos {
+		npos := *pos
+		npos.Conflicting = true
+		return &npos
+	}
+	require.Equal(t, ModelMap{
+		"/ip_rules/*/value": {pos1},
+		"/rules/0/a":        {conflicting(pos2)},
+		"/rules/1/a":        {conflicting(pos3)},
+		"/tags/*":           {pos4},
+	}, out)
+	require.Equal(t, []string{"/rules/*/a"}, conflicts)
+	// The input positions are not modified.
+	require.False(t, pos2.Conflicting)
+}
+
+func TestGeneralizePointer(t *testing.T) {
+	appModel := map[string]interface{}{
+		"list": []interface{}{
+			map[string]interface{}{"0": "b"},
+		},
+		"0": "c",
+	}
+	require.Equal(t, "/list/*/0", generalizePointer("/list/0/0", appModel, nil))
+	require.Equal(t, "/0", generalizePointer("/0", appModel, nil))
+}
+
+func TestGeneralizePointerMapKeyCollision(t *testing.T) {
+	appModel := map[string]interface{}{
+		"name": "a",
+		"tags": map[string]interface{}{
+			"name": "b",
+			"id":   "c",
+		},
+		"rules": []interface{}{
+			map[string]interface{}{"name": "d", "priority": 1},
+		},
+	}
+	keys := map[string]bool{"name": true, "id": true}
+	require.Equal(t, "/name", generalizePointer("/name", appModel, keys))
+	require.Equal(t, "/tags/*", generalizePointer("/tags/name", appModel, keys))
+	require.Equal(t, "/rules/*/name", generalizePointer("/rules/0/name", appModel, keys))
+}
+
+func TestMapVibration(t *testing.T) {
+	pos := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/state")}
+	vpos := &swagger.JSONValuePos{Addr: pos.Addr, Provenance: swagger.ProvenanceVibration, Confidence: 1}
+	withConditional := func(conditional string) *swagger.JSONValuePos {
+		npos := *vpos
+		npos.Conditional = conditional
+		npos.Confidence = 0.8
+		return &npos
+	}
+	cases := []struct {
+		name     string
+		base     map[string]interface{}
+		vibrated map[string]interface{}
+		presence bool
+		expect   SingleModelMap
+	}{
+		{
+			name:     "no diff",
+			base:     map[string]interface{}{"/state": "b"},
+			vibrated: map[string]interface{}{"/state": "b"},
+			expect:   nil,
+		},
+		{
+			name:     "single diff",
+			base:     map[string]interface{}{"/state": "b", "/name": "c"},
+			vibrated: map[string]interface{}{"/state": "d", "/name": "c"},
+			expect:   SingleModelMap{"/state": vpos},
+		},
+		{
+			name:     "multiple diffs",
+			base:     map[string]interface{}{"/state": "b", "/enabled": true},
+			vibrated: map[string]interface{}{"/state": "d", "/enabled": false},
+			expect:   SingleModelMap{"/state": vpos, "/enabled": vpos},
+		},
+		{
+			name:     "property set change",
+			base:     map[string]interface{}{"/state": "b", "/old": "c"},
+			vibrated: map[string]interface{}{"/state": "d", "/new": "e"},
+			expect: SingleModelMap{
+				"/state": vpos,
+				"/old":   withConditional(swagger.ConditionalDisappear),
+				"/new":   withConditional(swagger.ConditionalAppear),
+			},
+		},
+		{
+			name:     "presence",
+			base:     map[string]interface{}{"/rule/0/name": "b"},
+			vibrated: map[string]interface{}{},
+			presence: true,
+			expect: SingleModelMap{
+				"/rule/0/name": &swagger.JSONValuePos{Addr: pos.Addr, Conditional: swagger.ConditionalDisappear, Presence: true, Provenance: swagger.ProvenanceVibration, Confidence: 0.8},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expect, mapVibration(tt.base, tt.vibrated, pos, tt.presence))
+		})
+	}
+}
+
+func TestDeriveValueMapSynthesized(t *testing.T) {
+	// The initial string is as long as the ones of the mockserver, which derives the rnd of each request from its identity.
+	rnd := swagger.NewRnd(&swagger.RndOption{InitString: "abcdefaa"})
+	duration := rnd.NextString("duration")
+	b64url := rnd.NextString("base64url")
+	pos1 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/timeout")}
+	pos2 := &swagger.JSONValuePos{Addr: swagger.MustParseAddr("properties/secret")}
+	out := deriveValueMap(map[string]*swagger.JSONValuePos{
+		duration: pos1,
+		b64url:   pos2,
+	}, DefaultValueMatchers)
+
+	secs := matchDuration(duration)
+	require.NotEmpty(t, secs, duration)
+	require.Equal(t, "duration.seconds", out[secs[0].Value].Transform)
+	require.Equal(t, pos1.Addr, out[secs[0].Value].Addr)
+
+	decoded := matchBase64(b64url)
+	require.NotEmpty(t, decoded, b64url)
+	require.Equal(t, "base64.decode", out[decoded[0].Value].Transform)
+	require.Equal(t, pos2.Addr, out[decoded[0].Value].Addr)
+}
@@ -0,0 +1,179 @@
+package ctrl
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DerivedValue is a value derived from an API model leaf value by some transformation, as is commonly done by the
+// applications (e.g. lower-casing an enum).
+type DerivedValue struct {
+	Value string
+	// Transform explains how the value is derived, e.g. "armid.segment(resourceGroups)".
+	Transform string
+}
+
+// ValueMatcher derives the candidate application values from the string representation of an API model leaf value.
+// An application value that equals to one of the derived values is regarded as matched.
+type ValueMatcher interface {
+	Derive(val string) []DerivedValue
+}
+
+// ValueMatcherFunc is an adapter to allow the use of ordinary functions as ValueMatcher.
+type ValueMatcherFunc func(val string) []DerivedValue
+
+func (f ValueMatcherFunc) Derive(val string) []DerivedValue {
+	return f(val)
+}
+
+// DefaultValueMatchers are the matchers used when MapOption.Matchers is not specified.
+// The order matters: when more than one matchers derive the same value from the same API value, the former wins.
+var DefaultValueMatchers = []ValueMatcher{
+	ValueMatcherFunc(matchCase),
+	ValueMatcherFunc(matchARMID),
+	ValueMatcherFunc(matchSplit),
+	ValueMatcherFunc(matchDuration),
+	ValueMatcherFunc(matchBase64),
+	ValueMatcherFunc(matchTime),
+}
+
+var camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// matchCase derives the lower/upper cased value, and the snake cased value (e.g. "ReadWrite" -> "read_write").
+func matchCase(val string) []DerivedValue {
+	return []DerivedValue{
+		{Value: strings.ToLower(val), Transform: "lower"},
+		{Value: strings.ToUpper(val), Transform: "upper"},
+		{Value: strings.ToLower(camelBoundary.ReplaceAllString(val, "${1}_${2}")), Transform: "snake"},
+	}
+}
+
+// matchARMID derives each segment value of an ARM resource ID, e.g. "rg" from "/subscriptions/xxx/resourceGroups/rg", or "mg" from
+// the management group ID "/providers/Microsoft.Management/managementGroups/mg".
+func matchARMID(val string) []DerivedValue {
+	segs := strings.Split(strings.TrimPrefix(val, "/"), "/")
+	if len(segs)%2 != 0 {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(strings.ToLower(val), "/subscriptions/"):
+	case strings.HasPrefix(strings.ToLower(val), "/providers/"):
+		// The tenant level or management group IDs, which have at least the resource type and name following the provider.
+		if len(segs) < 4 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	var out []DerivedValue
+	for i := 0; i < len(segs); i += 2 {
+		out = append(out, DerivedValue{Value: segs[i+1], Transform: "armid.segment(" + segs[i] + ")"})
+	}
+	return out
+}
+
+// matchSplit derives each item of a delimited list, e.g. "a" and "b" from "a,b".
+func matchSplit(val string) []DerivedValue {
+	var out []DerivedValue
+	for _, sep := range []string{",", ";"} {
+		if !strings.Contains(val, sep) {
+			continue
+		}
+		for _, item := range strings.Split(val, sep) {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, DerivedValue{Value: item, Transform: "split(" + strconv.Quote(sep) + ")"})
+			}
+		}
+	}
+	return out
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// matchDuration derives the number of seconds from an ISO-8601 duration, e.g. "5400" from "PT1H30M", and the number of minutes if
+// it is whole minutes, e.g. "90" from "PT1H30M".
+func matchDuration(val string) []DerivedValue {
+	if val == "P" || val == "PT" {
+		return nil
+	}
+	sm := isoDuration.FindStringSubmatch(val)
+	if sm == nil {
+		return nil
+	}
+	var secs int
+	for i, unit := range []int{24 * 3600, 3600, 60, 1} {
+		if sm[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(sm[i+1])
+		if err != nil {
+			return nil
+		}
+		secs += n * unit
+	}
+	out := []DerivedValue{{Value: strconv.Itoa(secs), Transform: "duration.seconds"}}
+	if secs%60 == 0 {
+		out = append(out, DerivedValue{Value: strconv.Itoa(secs / 60), Transform: "duration.minutes"})
+	}
+	return out
+}
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.URLEncoding,
+	base64.RawStdEncoding,
+	base64.RawURLEncoding,
+}
+
+// minBase64Decoded is the minimum length of the decoded value, below which an ordinary string is too likely to decode by chance.
+const minBase64Decoded = 4
+
+// matchBase64 derives the decoded value of a base64 (or base64url, padded or not) encoded printable string.
+// The encodings are tried in order, the first one that decodes and encodes back to the string (i.e. it is canonical) wins.
+func matchBase64(val string) []DerivedValue {
+	for _, enc := range base64Encodings {
+		b, err := enc.DecodeString(val)
+		if err != nil || len(b) < minBase64Decoded || !utf8.Valid(b) || enc.EncodeToString(b) != val {
+			continue
+		}
+		printable := true
+		for _, r := range string(b) {
+			if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+				printable = false
+				break
+			}
+		}
+		if !printable {
+			continue
+		}
+		return []DerivedValue{{Value: string(b), Transform: "base64.decode"}}
+	}
+	return nil
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123,
+}
+
+// matchTime derives the reformatted value of a RFC3339 timestamp in some common layouts (in UTC).
+func matchTime(val string) []DerivedValue {
+	t, err := time.Parse(time.RFC3339Nano, val)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	var out []DerivedValue
+	for _, layout := range timeLayouts {
+		out = append(out, DerivedValue{Value: t.Format(layout), Transform: "time.format(" + layout + ")"})
+	}
+	return out
+}
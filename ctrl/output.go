@@ -0,0 +1,381 @@
+package ctrl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputFormatJSON    = "json"
+	OutputFormatYAML    = "yaml"
+	OutputFormatJSONL   = "jsonl"
+	OutputFormatTFState = "tfstate"
+)
+
+func validateOutput(output *Output) error {
+	if output == nil {
+		return nil
+	}
+	switch output.Format {
+	case "", OutputFormatJSON, OutputFormatYAML, OutputFormatJSONL:
+		if output.Resource != "" {
+			return fmt.Errorf("output `resource` can only be specified for the %q format", OutputFormatTFState)
+		}
+	case OutputFormatTFState:
+		if output.Resource == "" {
+			return fmt.Errorf("output `resource` must be specified for the %q format", OutputFormatTFState)
+		}
+		if _, err := parseResourceAddr(output.Resource); err != nil {
+			return fmt.Errorf("parsing output `resource` %q: %v", output.Resource, err)
+		}
+	default:
+		return fmt.Errorf("unknown output `format` %q", output.Format)
+	}
+	if output.Query != "" {
+		if _, err := parseQuery(output.Query); err != nil {
+			return fmt.Errorf("parsing output `query` %q: %v", output.Query, err)
+		}
+	}
+	return nil
+}
+
+// parseOutput parses the stdout of an execution into the application model, which can be any JSON value.
+// A nil output means the JSON format.
+func parseOutput(b []byte, output *Output) (interface{}, error) {
+	if output == nil {
+		output = &Output{}
+	}
+	var (
+		v   interface{}
+		err error
+	)
+	switch output.Format {
+	case "", OutputFormatJSON:
+		v, err = decodeJSON(b)
+	case OutputFormatYAML:
+		v, err = decodeYAML(b)
+	case OutputFormatJSONL:
+		v, err = decodeJSONLines(b)
+	case OutputFormatTFState:
+		v, err = decodeTFState(b, output.Resource)
+	default:
+		return nil, fmt.Errorf("unknown output format %q", output.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if output.Query != "" {
+		q, err := parseQuery(output.Query)
+		if err != nil {
+			return nil, fmt.Errorf("parsing query %q: %v", output.Query, err)
+		}
+		v, err = q.eval(v)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating query %q: %v", output.Query, err)
+		}
+	}
+	return v, nil
+}
+
+// decodeJSON decodes the JSON value. If the output is mixed with log noise, the first JSON object or array that starts a line is decoded.
+func decodeJSON(b []byte) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	if err == nil {
+		return v, nil
+	}
+	for offset := 0; offset < len(b); {
+		line := b[offset:]
+		if idx := bytes.IndexByte(line, '\n'); idx != -1 {
+			line = line[:idx+1]
+		}
+		if trimmed := bytes.TrimLeft(line, " \t"); len(trimmed) != 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			var nv interface{}
+			if json.NewDecoder(bytes.NewReader(b[offset:])).Decode(&nv) == nil {
+				return nv, nil
+			}
+		}
+		offset += len(line)
+	}
+	return nil, err
+}
+
+// decodeYAML decodes the YAML document into its JSON representation.
+func decodeYAML(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	jb, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("converting YAML to JSON: %v", err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(jb, &out); err != nil {
+		return nil, fmt.Errorf("converting YAML to JSON: %v", err)
+	}
+	return out, nil
+}
+
+// decodeJSONLines decodes the JSON Lines into an array. The lines that are not JSON values (e.g. log noise) are skipped.
+func decodeJSONLines(b []byte) (interface{}, error) {
+	out := []interface{}{}
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(line, &v); err != nil {
+			continue
+		}
+		out = append(out, v)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no JSON line found")
+	}
+	return out, nil
+}
+
+type tfstate struct {
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey   interface{} `json:"index_key"`
+			Attributes interface{} `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// resourceAddr is a terraform resource instance address, e.g. `module.foo.azurerm_resource_group.test[0]`.
+type resourceAddr struct {
+	Module string
+	Mode   string
+	Type   string
+	Name   string
+	// Index is either nil, a float64 or a string.
+	Index interface{}
+}
+
+func parseResourceAddr(input string) (*resourceAddr, error) {
+	addr := &resourceAddr{Mode: "managed"}
+	s := input
+	if strings.HasSuffix(s, "]") {
+		idx := strings.LastIndex(s, "[")
+		if idx == -1 {
+			return nil, fmt.Errorf("unbalanced brackets")
+		}
+		key := s[idx+1 : len(s)-1]
+		if strings.HasPrefix(key, `"`) {
+			v, err := strconv.Unquote(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index key %s: %v", key, err)
+			}
+			addr.Index = v
+		} else {
+			v, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index key %s: %v", key, err)
+			}
+			addr.Index = float64(v)
+		}
+		s = s[:idx]
+	}
+	segs := strings.Split(s, ".")
+	if len(segs) < 2 {
+		return nil, fmt.Errorf("expect the address to contain at least the resource type and name")
+	}
+	addr.Type, addr.Name = segs[len(segs)-2], segs[len(segs)-1]
+	segs = segs[:len(segs)-2]
+	if n := len(segs); n != 0 && segs[n-1] == "data" && (n == 1 || segs[n-2] != "module") {
+		addr.Mode = "data"
+		segs = segs[:n-1]
+	}
+	addr.Module = strings.Join(segs, ".")
+	if addr.Type == "" || addr.Name == "" {
+		return nil, fmt.Errorf("empty resource type or name")
+	}
+	return addr, nil
+}
+
+// decodeTFState decodes the terraform state (version 4) and returns the attributes of the specified resource instance.
+func decodeTFState(b []byte, resource string) (interface{}, error) {
+	addr, err := parseResourceAddr(resource)
+	if err != nil {
+		return nil, fmt.Errorf("parsing resource address %q: %v", resource, err)
+	}
+	var state tfstate
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("unmarshal terraform state: %v", err)
+	}
+	for _, res := range state.Resources {
+		if res.Module != addr.Module || res.Mode != addr.Mode || res.Type != addr.Type || res.Name != addr.Name {
+			continue
+		}
+		for _, ins := range res.Instances {
+			if ins.IndexKey == addr.Index {
+				return ins.Attributes, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("resource %q not found in the terraform state", resource)
+}
+
+// query is a jq-style path expression, which supports the identity (`.`), the object identifier index (`.foo`, `."foo"`, `.["foo"]`),
+// the array index (`.[0]`, `.[-1]`) and the iterator (`.[]`).
+type query []queryStep
+
+type queryStep struct {
+	Key     *string
+	Index   *int
+	Iterate bool
+}
+
+func parseQuery(expr string) (query, error) {
+	if !strings.HasPrefix(expr, ".") {
+		return nil, fmt.Errorf("expect the expression to start with `.`")
+	}
+	if expr == "." {
+		return query{}, nil
+	}
+	var q query
+	s := expr
+	for len(s) != 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			switch {
+			case s == "":
+				return nil, fmt.Errorf("unexpected end after `.`")
+			case s[0] == '[':
+				continue
+			case s[0] == '"':
+				key, rest, err := parseQuoted(s)
+				if err != nil {
+					return nil, err
+				}
+				q = append(q, queryStep{Key: &key})
+				s = rest
+			default:
+				n := 0
+				for n < len(s) && (s[n] == '_' || 'a' <= s[n] && s[n] <= 'z' || 'A' <= s[n] && s[n] <= 'Z' || n != 0 && '0' <= s[n] && s[n] <= '9') {
+					n++
+				}
+				if n == 0 {
+					return nil, fmt.Errorf("unexpected character %q", s[0])
+				}
+				key := s[:n]
+				q = append(q, queryStep{Key: &key})
+				s = s[n:]
+			}
+		case '[':
+			s = s[1:]
+			switch {
+			case strings.HasPrefix(s, "]"):
+				q = append(q, queryStep{Iterate: true})
+				s = s[1:]
+			case strings.HasPrefix(s, `"`):
+				key, rest, err := parseQuoted(s)
+				if err != nil {
+					return nil, err
+				}
+				if !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("expect `]` after %q", key)
+				}
+				q = append(q, queryStep{Key: &key})
+				s = rest[1:]
+			default:
+				idx := strings.Index(s, "]")
+				if idx == -1 {
+					return nil, fmt.Errorf("unbalanced brackets")
+				}
+				n, err := strconv.Atoi(s[:idx])
+				if err != nil {
+					return nil, fmt.Errorf("invalid array index %q", s[:idx])
+				}
+				q = append(q, queryStep{Index: &n})
+				s = s[idx+1:]
+			}
+		default:
+			return nil, fmt.Errorf("unexpected character %q", s[0])
+		}
+	}
+	return q, nil
+}
+
+func parseQuoted(s string) (string, string, error) {
+	quoted, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid quoted string: %v", err)
+	}
+	v, _ := strconv.Unquote(quoted)
+	return v, s[len(quoted):], nil
+}
+
+// eval evaluates the query against the JSON value. As is in jq, indexing a null or a missing key results in null.
+// If the query contains any iterator, the results are collected into an array.
+func (q query) eval(v interface{}) (interface{}, error) {
+	vals := []interface{}{v}
+	var iterated bool
+	for _, step := range q {
+		var nvals []interface{}
+		for _, val := range vals {
+			switch {
+			case step.Iterate:
+				iterated = true
+				switch val := val.(type) {
+				case []interface{}:
+					nvals = append(nvals, val...)
+				case map[string]interface{}:
+					for _, k := range sortedKeys(val) {
+						nvals = append(nvals, val[k])
+					}
+				default:
+					return nil, fmt.Errorf("cannot iterate over %T", val)
+				}
+			case step.Key != nil:
+				switch val := val.(type) {
+				case nil:
+					nvals = append(nvals, nil)
+				case map[string]interface{}:
+					nvals = append(nvals, val[*step.Key])
+				default:
+					return nil, fmt.Errorf("cannot index %T with %q", val, *step.Key)
+				}
+			case step.Index != nil:
+				switch val := val.(type) {
+				case nil:
+					nvals = append(nvals, nil)
+				case []interface{}:
+					idx := *step.Index
+					if idx < 0 {
+						idx += len(val)
+					}
+					if idx < 0 || idx >= len(val) {
+						nvals = append(nvals, nil)
+						continue
+					}
+					nvals = append(nvals, val[idx])
+				default:
+					return nil, fmt.Errorf("cannot index %T with %d", val, *step.Index)
+				}
+			}
+		}
+		vals = nvals
+	}
+	if iterated {
+		if vals == nil {
+			vals = []interface{}{}
+		}
+		return vals, nil
+	}
+	return vals[0], nil
+}
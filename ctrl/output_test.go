@@ -0,0 +1,175 @@
+package ctrl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutput(t *testing.T) {
+	tfstate := `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "azurerm_resource_group",
+      "name": "test",
+      "instances": [{"attributes": {"name": "rg"}}]
+    },
+    {
+      "module": "module.foo",
+      "mode": "data",
+      "type": "azurerm_resource_group",
+      "name": "test",
+      "instances": [
+        {"index_key": 0, "attributes": {"name": "rg0"}},
+        {"index_key": 1, "attributes": {"name": "rg1"}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "azurerm_resource_group",
+      "name": "each",
+      "instances": [{"index_key": "a", "attributes": {"name": "rga"}}]
+    }
+  ]
+}`
+	cases := []struct {
+		name   string
+		input  string
+		output *Output
+		expect interface{}
+		err    bool
+	}{
+		{
+			name:   "json object",
+			input:  `{"a": 1}`,
+			expect: map[string]interface{}{"a": float64(1)},
+		},
+		{
+			name:   "json array",
+			input:  `[1, "a"]`,
+			output: &Output{Format: OutputFormatJSON},
+			expect: []interface{}{float64(1), "a"},
+		},
+		{
+			name:   "json primitive",
+			input:  `"a"`,
+			expect: "a",
+		},
+		{
+			name:   "json with log noise",
+			input:  "[INFO] starting {\n{\n  \"a\": [1]\n}\n[INFO] done\n",
+			expect: map[string]interface{}{"a": []interface{}{float64(1)}},
+		},
+		{
+			name:  "invalid json",
+			input: "[INFO] nothing",
+			err:   true,
+		},
+		{
+			name:   "yaml",
+			input:  "a: 1\nb:\n  - c\n",
+			output: &Output{Format: OutputFormatYAML},
+			expect: map[string]interface{}{"a": float64(1), "b": []interface{}{"c"}},
+		},
+		{
+			name:   "jsonl",
+			input:  "{\"a\": 1}\nnoise\n\n{\"a\": 2}\n",
+			output: &Output{Format: OutputFormatJSONL},
+			expect: []interface{}{map[string]interface{}{"a": float64(1)}, map[string]interface{}{"a": float64(2)}},
+		},
+		{
+			name:   "jsonl without json line",
+			input:  "noise\n",
+			output: &Output{Format: OutputFormatJSONL},
+			err:    true,
+		},
+		{
+			name:   "tfstate",
+			input:  tfstate,
+			output: &Output{Format: OutputFormatTFState, Resource: "azurerm_resource_group.test"},
+			expect: map[string]interface{}{"name": "rg"},
+		},
+		{
+			name:   "tfstate in module with index",
+			input:  tfstate,
+			output: &Output{Format: OutputFormatTFState, Resource: "module.foo.data.azurerm_resource_group.test[1]"},
+			expect: map[string]interface{}{"name": "rg1"},
+		},
+		{
+			name:   "tfstate with key",
+			input:  tfstate,
+			output: &Output{Format: OutputFormatTFState, Resource: `azurerm_resource_group.each["a"]`},
+			expect: map[string]interface{}{"name": "rga"},
+		},
+		{
+			name:   "tfstate resource not found",
+			input:  tfstate,
+			output: &Output{Format: OutputFormatTFState, Resource: "azurerm_resource_group.test[0]"},
+			err:    true,
+		},
+		{
+			name:   "query",
+			input:  `{"data": {"items": [{"name": "a"}, {"name": "b"}]}}`,
+			output: &Output{Query: ".data.items[-1]"},
+			expect: map[string]interface{}{"name": "b"},
+		},
+		{
+			name:   "query with iterator",
+			input:  `{"data": {"items": [{"name": "a"}, {"name": "b"}]}}`,
+			output: &Output{Query: `.data["items"][].name`},
+			expect: []interface{}{"a", "b"},
+		},
+		{
+			name:   "query missing key",
+			input:  `{"data": {}}`,
+			output: &Output{Query: `.data."not exist".foo`},
+			expect: nil,
+		},
+		{
+			name:   "query indexing a string",
+			input:  `{"data": "a"}`,
+			output: &Output{Query: `.data.foo`},
+			err:    true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := parseOutput([]byte(tt.input), tt.output)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, out)
+		})
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	cases := []struct {
+		name   string
+		output *Output
+		err    bool
+	}{
+		{name: "nil"},
+		{name: "yaml", output: &Output{Format: OutputFormatYAML, Query: ".[0]"}},
+		{name: "unknown format", output: &Output{Format: "hcl"}, err: true},
+		{name: "tfstate without resource", output: &Output{Format: OutputFormatTFState}, err: true},
+		{name: "resource without tfstate", output: &Output{Resource: "a.b"}, err: true},
+		{name: "invalid resource", output: &Output{Format: OutputFormatTFState, Resource: "a[x]"}, err: true},
+		{name: "invalid query", output: &Output{Query: "foo"}, err: true},
+		{name: "unbalanced query", output: &Output{Query: ".a[0"}, err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOutput(tt.output)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
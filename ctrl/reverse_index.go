@@ -0,0 +1,67 @@
+package ctrl
+
+import (
+	"sort"
+)
+
+const (
+	OutputModeModelMap     = "model_map"
+	OutputModeReverseIndex = "reverse_index"
+)
+
+// ReverseIndex maps the spec file and the JSON pointer (within the file) of an API property definition to the application properties
+// that are mapped to it, across all the executions.
+type ReverseIndex map[string]map[string][]ReverseIndexEntry
+
+type ReverseIndexEntry struct {
+	// Execution is the execution name.
+	Execution string `json:"execution"`
+	// AppProperty is the pointer of the application property.
+	AppProperty string `json:"app_property"`
+}
+
+// NewReverseIndex inverts the results, which maps the execution name to its model map, into a ReverseIndex.
+func NewReverseIndex(results map[string]ModelMap) ReverseIndex {
+	index := ReverseIndex{}
+	seen := map[string]bool{}
+	for execName, mm := range results {
+		for appProp, poses := range mm {
+			for _, pos := range poses {
+				if pos == nil {
+					continue
+				}
+				var file string
+				if u := pos.Ref.GetURL(); u != nil {
+					file = u.Path
+				}
+				ptr := pos.Ref.GetPointer().String()
+				entry := ReverseIndexEntry{
+					Execution:   execName,
+					AppProperty: appProp,
+				}
+				key := file + "#" + ptr + ":" + execName + ":" + appProp
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				m, ok := index[file]
+				if !ok {
+					m = map[string][]ReverseIndexEntry{}
+					index[file] = m
+				}
+				m[ptr] = append(m[ptr], entry)
+			}
+		}
+	}
+	for _, m := range index {
+		for _, entries := range m {
+			sort.Slice(entries, func(i, j int) bool {
+				if entries[i].Execution != entries[j].Execution {
+					return entries[i].Execution < entries[j].Execution
+				}
+				return entries[i].AppProperty < entries[j].AppProperty
+			})
+		}
+	}
+	return index
+}
@@ -0,0 +1,43 @@
+package ctrl
+
+import (
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReverseIndex(t *testing.T) {
+	name := &swagger.JSONValuePos{Ref: jsonreference.MustCreateRef("foo.json#/definitions/Foo/properties/name")}
+	keySource := &swagger.JSONValuePos{Ref: jsonreference.MustCreateRef("foo.json#/definitions/Encryption/properties/keySource")}
+	tag := &swagger.JSONValuePos{Ref: jsonreference.MustCreateRef("common.json#/definitions/Resource/properties/tags/additionalProperties")}
+	results := map[string]ModelMap{
+		"foo": {
+			"/name":       {name},
+			"/key_source": {keySource},
+			"/tags/*":     {tag},
+		},
+		"bar": {
+			"/encryption/0/key_source": {keySource},
+			"/tags/*":                  {tag, tag},
+		},
+	}
+	require.Equal(t, ReverseIndex{
+		"foo.json": {
+			"/definitions/Foo/properties/name": {
+				{Execution: "foo", AppProperty: "/name"},
+			},
+			"/definitions/Encryption/properties/keySource": {
+				{Execution: "bar", AppProperty: "/encryption/0/key_source"},
+				{Execution: "foo", AppProperty: "/key_source"},
+			},
+		},
+		"common.json": {
+			"/definitions/Resource/properties/tags/additionalProperties": {
+				{Execution: "bar", AppProperty: "/tags/*"},
+				{Execution: "foo", AppProperty: "/tags/*"},
+			},
+		},
+	}, NewReverseIndex(results))
+}
@@ -13,10 +13,10 @@ require (
 	github.com/hashicorp/hcl/v2 v2.16.2
 	github.com/magodo/azure-rest-api-index v0.0.0-20230522080218-497fe558c02f
 	github.com/magodo/jsonpointerpos v0.0.0-20230616092109-ca8d78efc96d
-	github.com/rickb777/date v1.20.1
 	github.com/stretchr/testify v1.8.1
 	github.com/zclconf/go-cty v1.12.1
 	golang.org/x/exp v0.0.0-20230811145659-89c5cff77bcb
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -55,7 +55,6 @@ require (
 	github.com/pjbgf/sha1cd v0.3.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/rickb777/plural v1.4.1 // indirect
 	github.com/sergi/go-diff v1.1.0 // indirect
 	github.com/skeema/knownhosts v1.1.0 // indirect
 	github.com/xanzy/ssh-agent v0.3.3 // indirect
@@ -65,5 +64,4 @@ require (
 	golang.org/x/sys v0.5.0 // indirect
 	golang.org/x/text v0.7.0 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
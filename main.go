@@ -24,6 +24,11 @@ func main() {
 	execFrom := flag.String("from", "", "Run execution from the specified one (inclusively), in form of `name.type`")
 	execTo := flag.String("to", "", "Run execution until the specified one (exclusively), in form of `name.type`")
 	timeout := flag.Int("timeout", 60, "The mock server read/write timeout in second")
+	cacheDir := flag.String("cache-dir", "", "The directory to persist the expanded swagger models across runs")
+	coverage := flag.String("coverage", "", "The file to write the coverage report to")
+	coverageFormat := flag.String("coverage-format", "json", "The format of the coverage report, can be `json` or `markdown`")
+	minConfidence := flag.Float64("min-confidence", 0, "The confidence threshold in the range of [0, 1], the mappings whose confidence is lower than it are dropped from the output")
+	outputMode := flag.String("output-mode", "model_map", "The output mode, can be `model_map` (keyed by the app properties) or `reverse_index` (keyed by the API property definitions)")
 
 	flag.Parse()
 
@@ -51,8 +56,13 @@ func main() {
 			SpecDir: specAbsPath,
 			Timeout: time.Duration(*timeout) * time.Second,
 		},
-		ExecFrom: *execFrom,
-		ExecTo:   *execTo,
+		ExecFrom:       *execFrom,
+		ExecTo:         *execTo,
+		CacheDir:       *cacheDir,
+		CoverageFile:   *coverage,
+		CoverageFormat: *coverageFormat,
+		OutputMode:     *outputMode,
+		MinConfidence:  *minConfidence,
 	})
 	if err != nil {
 		log.Error(err.Error())
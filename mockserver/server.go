@@ -5,12 +5,15 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	jsonpatch "github.com/evanphx/json-patch/v5"
@@ -31,15 +34,73 @@ type Server struct {
 	Idx     azidx.Index
 	Specdir string
 
+	// mu protects the following execution-based and sub-execution-based states, as the requests can be handled concurrently.
+	mu sync.Mutex
+
 	// Followings are execution-based
-	rnd       swagger.Rnd
 	overrides Overrides
-	records   []swagger.JSONValue
-	seqs      []MonoModelDesc
 
 	// Following are sub-execution-based
+	// The records and seqs are keyed by the request identity, so that they are not subject to the arrival order of the concurrent requests.
+	occurrences map[string]int
+	records     map[requestKey]record
+	seqs        map[requestKey]MonoModelDesc
+
 	vibration       *Vibration
 	vibrationRecord *swagger.JSONValue
+	vibrationPath   string
+}
+
+// requestKey identifies a request within a (sub-)execution, by its method, path and the occurrence of the same method and path.
+type requestKey struct {
+	Method     string
+	Path       string
+	Occurrence int
+}
+
+func (key requestKey) less(okey requestKey) bool {
+	if key.Method != okey.Method {
+		return key.Method < okey.Method
+	}
+	if key.Path != okey.Path {
+		return key.Path < okey.Path
+	}
+	return key.Occurrence < okey.Occurrence
+}
+
+// rndBaseTime is the base of the time values synthesized by the rnd of each request.
+var rndBaseTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// rnd returns the rnd derived from the request key, so that the same request is always synthesized with the same values.
+// The seed of the key allots each request a block of values, which is unlikely to overlap with the others'.
+func (key requestKey) rnd() swagger.Rnd {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s %s %d", key.Method, key.Path, key.Occurrence)
+	seed := h.Sum64()
+
+	// 6 letters followed by "aa", which leaves 676 strings to each request before it carries over to the seed part.
+	letters := make([]byte, 8)
+	n := seed
+	for i := 0; i < 6; i++ {
+		letters[i] = byte('a' + n%26)
+		n /= 26
+	}
+	letters[6], letters[7] = 'a', 'a'
+
+	// The top 24 bits of the seed, each leaves 65536 integers (and numbers) to each request.
+	integer := int64(seed>>40) << 16
+	return swagger.NewRnd(&swagger.RndOption{
+		InitString:  string(letters),
+		InitInteger: integer,
+		InitNumber:  float64(integer) + 0.5,
+		// Each request is left with 64 days.
+		InitTime: rndBaseTime.AddDate(0, 0, int(seed%(1<<12))*64),
+	})
+}
+
+type record struct {
+	value swagger.JSONValue
+	model *swagger.Property
 }
 
 type Overrides []Override
@@ -51,6 +112,8 @@ type Override struct {
 
 	ResponseSelectorMerge string
 	ResponseSelectorJSON  string
+	// ResponseSelectorVariant fixes the variant choices of the polymorphic properties in the response model.
+	ResponseSelectorVariant []swagger.PropertyAddr
 
 	ResponseBody       string
 	ResponsePatchMerge string
@@ -59,14 +122,56 @@ type Override struct {
 	ResponseHeader     map[string]string
 	ResponseStatusCode int
 
+	// MaxInstances limits the number of the monomorphized instances to enumerate when synthesizing the response.
+	// 0 means DefaultMaxInstances.
+	MaxInstances int
+
 	SynthOption    *swagger.SynthesizerOption
 	ExpanderOption *swagger.ExpanderOption
 }
 
+const (
+	// VibrationOpReplace replaces the (primitive) value at the path.
+	VibrationOpReplace = "replace"
+	// VibrationOpRemove removes the value at the path.
+	VibrationOpRemove = "remove"
+	// VibrationOpAdd adds the value (can be an object or an array) at the path.
+	VibrationOpAdd = "add"
+	// VibrationOpDuplicate duplicates the array element at the path, by appending a copy of it to the array.
+	VibrationOpDuplicate = "duplicate"
+)
+
 type Vibration struct {
 	PathPattern regexp.Regexp
-	Path        string
-	Value       interface{}
+	// Path is the JSON pointer to the vibrated value. It is ignored if Addr is specified.
+	Path string
+	// Addr is the property address of the vibrated value, which is resolved against the response to be vibrated.
+	Addr *swagger.PropertyAddr
+	// Op is one of the VibrationOpXXX, defaults to VibrationOpReplace.
+	Op    string
+	Value interface{}
+}
+
+// patch returns the JSON patch that applies the vibration at the path.
+func (vib Vibration) patch(path string) ([]map[string]interface{}, error) {
+	switch vib.Op {
+	case "", VibrationOpReplace, VibrationOpAdd:
+		op := vib.Op
+		if op == "" {
+			op = VibrationOpReplace
+		}
+		return []map[string]interface{}{{"op": op, "path": path, "value": vib.Value}}, nil
+	case VibrationOpRemove:
+		return []map[string]interface{}{{"op": "remove", "path": path}}, nil
+	case VibrationOpDuplicate:
+		idx := strings.LastIndex(path, "/")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid path to duplicate: %s", path)
+		}
+		return []map[string]interface{}{{"op": "copy", "from": path, "path": path[:idx] + "/-"}}, nil
+	default:
+		return nil, fmt.Errorf("unknown vibration op %q", vib.Op)
+	}
 }
 
 func (ovs Overrides) Match(path string) *Override {
@@ -86,8 +191,13 @@ type MonoModelDesc struct {
 	APIVersion string
 	Operation  string
 	SelIndex   int
+	// The total number of the monomorphized instances (after pruning by the response selector).
+	Instances int
 }
 
+// DefaultMaxInstances is the default number of the monomorphized instances to enumerate when synthesizing a response.
+const DefaultMaxInstances = 1000
+
 type Option struct {
 	Addr    string
 	Port    int
@@ -116,8 +226,12 @@ func New(opt Option) (*Server, error) {
 }
 
 func (srv *Server) writeError(w http.ResponseWriter, err error) {
+	srv.writeErrorWithStatus(w, http.StatusInternalServerError, err)
+}
+
+func (srv *Server) writeErrorWithStatus(w http.ResponseWriter, statusCode int, err error) {
 	log.Error(err.Error())
-	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(statusCode)
 	w.Write([]byte(fmt.Sprintf(`{"error": %q}`, err.Error())))
 }
 
@@ -143,7 +257,12 @@ func (srv *Server) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	srv.mu.Lock()
 	ov := srv.overrides.Match(r.URL.Path)
+	key := requestKey{Method: r.Method, Path: r.URL.Path}
+	key.Occurrence = srv.occurrences[key.Method+" "+key.Path]
+	srv.occurrences[key.Method+" "+key.Path]++
+	srv.mu.Unlock()
 
 	// Override response body, just return the hardcoded response body
 	if ov != nil && ov.ResponseBody != "" {
@@ -161,22 +280,8 @@ func (srv *Server) Handle(w http.ResponseWriter, r *http.Request) {
 		srv.modifyRequest(r, *ov.RequestModify)
 	}
 
-	var (
-		synthOpt    *swagger.SynthesizerOption
-		expanderOpt *swagger.ExpanderOption
-	)
-	if ov != nil {
-		synthOpt = ov.SynthOption
-		expanderOpt = ov.ExpanderOption
-	}
-
-	resps, expRoot, err := srv.synthResponse(r, synthOpt, expanderOpt)
-	if err != nil {
-		srv.writeError(w, err)
-		return
-	}
-
-	selIdx, responseBody, err := srv.selResponse(resps, ov)
+	rnd := key.rnd()
+	selIdx, instances, responseBody, expRoot, instance, err := srv.synthResponse(r, ov, &rnd)
 	if err != nil {
 		srv.writeError(w, err)
 		return
@@ -187,8 +292,11 @@ func (srv *Server) Handle(w http.ResponseWriter, r *http.Request) {
 		APIVersion: r.URL.Query().Get("api-version"),
 		Operation:  r.Method,
 		SelIndex:   selIdx,
+		Instances:  instances,
 	}
-	srv.seqs = append(srv.seqs, modelDesc)
+	srv.mu.Lock()
+	srv.seqs[key] = modelDesc
+	srv.mu.Unlock()
 
 	if ov != nil {
 		switch {
@@ -215,22 +323,43 @@ func (srv *Server) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var vibrateOK bool
-	responseBody, vibrateOK, err = srv.vibrateResponse(*r.URL, responseBody)
+	unvibratedBody := responseBody
+	var vibrationPath string
+	responseBody, vibrationPath, vibrateOK, err = srv.vibrateResponse(*r.URL, responseBody, instance)
 	if err != nil {
 		srv.writeError(w, err)
 		return
 	}
 
-	v, err := swagger.UnmarshalJSONToJSONValue(responseBody, expRoot)
+	// The JSON value is built from the served instance, whose variants are the ones actually synthesized.
+	v, err := swagger.UnmarshalJSONToJSONValue(responseBody, instance)
 	if err != nil {
+		if vibrateOK {
+			// The vibration (e.g. adding a polymorphic object without its discriminator) is the culprit, as the unvibrated response is synthesized from the API model.
+			srv.writeErrorWithStatus(w, http.StatusBadRequest, fmt.Errorf("the vibrated response doesn't conform to the API model, check the vibration value: %v", err))
+			return
+		}
 		srv.writeError(w, fmt.Errorf("unmarshal JSON to JSONValue: %v", err))
 		return
 	}
-	srv.records = append(srv.records, v)
 
+	// The vibration record is expected to contain the vibrated path, which is not the case for the removal.
+	vibrationRecord := v
+	if vibrateOK && srv.vibrationOp() == VibrationOpRemove {
+		vibrationRecord, err = swagger.UnmarshalJSONToJSONValue(unvibratedBody, instance)
+		if err != nil {
+			srv.writeError(w, fmt.Errorf("unmarshal JSON to JSONValue: %v", err))
+			return
+		}
+	}
+
+	srv.mu.Lock()
+	srv.records[key] = record{value: v, model: expRoot}
 	if vibrateOK {
-		srv.vibrationRecord = &v
+		srv.vibrationRecord = &vibrationRecord
+		srv.vibrationPath = vibrationPath
 	}
+	srv.mu.Unlock()
 
 	log.Debug("server handler", "response", string(responseBody))
 	srv.setHeader(w, r, ov)
@@ -251,146 +380,250 @@ func (srv *Server) modifyRequest(req *http.Request, desc swagger.RequestDescript
 	}
 }
 
-func (srv *Server) vibrateResponse(uRL url.URL, response []byte) ([]byte, bool, error) {
-	if srv.vibration == nil || !srv.vibration.PathPattern.MatchString(uRL.Path) {
-		return response, false, nil
+func (srv *Server) vibrationOp() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.vibration == nil {
+		return ""
 	}
+	return srv.vibration.Op
+}
 
-	vibratePatchRaw, err := json.Marshal([]map[string]interface{}{
-		{
-			"op":    "replace",
-			"path":  srv.vibration.Path,
-			"value": srv.vibration.Value,
-		},
-	})
+// vibrateResponse vibrates the response if the vibration applies to the URL, and returns the vibrated response together with the JSON pointer
+// to the vibrated value.
+func (srv *Server) vibrateResponse(uRL url.URL, response []byte, root *swagger.Property) ([]byte, string, bool, error) {
+	srv.mu.Lock()
+	vibration := srv.vibration
+	srv.mu.Unlock()
+
+	if vibration == nil || !vibration.PathPattern.MatchString(uRL.Path) {
+		return response, "", false, nil
+	}
+
+	path := vibration.Path
+	if vibration.Addr != nil {
+		v, err := swagger.UnmarshalJSONToJSONValue(response, root)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("unmarshal JSON to JSONValue: %v", err)
+		}
+		path, err = swagger.ResolveAddrPointer(v, *vibration.Addr)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("resolving vibration address %s: %v", vibration.Addr, err)
+		}
+	}
+
+	vibratePatch, err := vibration.patch(path)
+	if err != nil {
+		return nil, "", false, err
+	}
+	vibratePatchRaw, err := json.Marshal(vibratePatch)
 	if err != nil {
-		return nil, false, err
+		return nil, "", false, err
 	}
 	patch, err := jsonpatch.DecodePatch(vibratePatchRaw)
 	if err != nil {
-		return nil, false, fmt.Errorf("decoding patch %v: %v", string(vibratePatchRaw), err)
+		return nil, "", false, fmt.Errorf("decoding patch %v: %v", string(vibratePatchRaw), err)
 	}
 	log.Debug("vibrate", "url", uRL, "patch", string(vibratePatchRaw))
 	b, err := patch.Apply(response)
 	if err != nil {
-		return nil, false, err
+		return nil, "", false, err
 	}
-	return b, true, nil
+	return b, path, true, nil
 }
 
-func (srv *Server) synthResponse(r *http.Request, synthOpt *swagger.SynthesizerOption, expanderOpt *swagger.ExpanderOption) ([]interface{}, *swagger.Property, error) {
+// synthResponse synthesizes the response of the request. The monomorphized instances of the response model are enumerated
+// lazily, until the one selected by the response selector is found.
+// It returns the index of the selected instance, the total number of the instances, the response, the expanded response model and the
+// selected (monomorphized) instance of it.
+func (srv *Server) synthResponse(r *http.Request, ov *Override, rnd *swagger.Rnd) (int, int, []byte, *swagger.Property, *swagger.Property, error) {
+	var (
+		synthOpt     *swagger.SynthesizerOption
+		expanderOpt  *swagger.ExpanderOption
+		variants     []swagger.PropertyAddr
+		maxInstances = DefaultMaxInstances
+	)
+	if ov != nil {
+		synthOpt = ov.SynthOption
+		expanderOpt = ov.ExpanderOption
+		variants = ov.ResponseSelectorVariant
+		if ov.MaxInstances != 0 {
+			maxInstances = ov.MaxInstances
+		}
+	}
+
 	ref, err := srv.Idx.Lookup(r.Method, *r.URL)
 	if err != nil {
-		return nil, nil, err
+		return 0, 0, nil, nil, nil, err
 	}
 	exp, err := swagger.NewExpanderFromOpRef(spec.MustCreateRef(filepath.Join(srv.Specdir, ref.GetURL().Path)+"#"+ref.GetPointer().String()), expanderOpt)
 	if err != nil {
-		return nil, nil, err
+		return 0, 0, nil, nil, nil, err
 	}
 	if err := exp.Expand(); err != nil {
-		return nil, nil, err
+		return 0, 0, nil, nil, nil, err
 	}
-	modelInstances := swagger.Monomorphization(exp.Root())
-	var results []interface{}
-	for _, modelInstance := range modelInstances {
-		modelInstance := modelInstance
-		syn, err := swagger.NewSynthesizer(&modelInstance, &srv.rnd, synthOpt)
-		if err != nil {
-			return nil, nil, err
-		}
-		if sv, ok := syn.Synthesize(); ok {
-			results = append(results, sv)
-		}
+	selector, err := responseSelectorValue(ov)
+	if err != nil {
+		return 0, 0, nil, nil, nil, err
 	}
-	return results, exp.Root(), nil
-}
-
-func (srv *Server) selResponse(resps []interface{}, ov *Override) (int, []byte, error) {
-	if len(resps) == 0 {
-		return 0, nil, fmt.Errorf("no responses to select")
+	mono, err := swagger.NewMonomorphizer(exp.Root(), &swagger.MonomorphizationOption{
+		KeepArrayElementVariants: synthOpt != nil && synthOpt.ArrayElementVariants,
+		Selector:                 selector,
+		Variants:                 variants,
+		MaxInstances:             maxInstances,
+	})
+	if err != nil {
+		return 0, 0, nil, nil, nil, fmt.Errorf("monomorphizing the response model: %v", err)
 	}
-
-	if len(resps) == 1 {
-		b, err := json.Marshal(resps[0])
-		return 0, b, err
+	log.Debug("monomorphization", "url", r.URL.String(), "instances", mono.Total(), "max_instances", maxInstances)
+	if mono.Total() > mono.Len() {
+		log.Warn(fmt.Sprintf("only the first %d of %d monomorphized instances are enumerated, set the override's `max_instances` to enumerate more", mono.Len(), mono.Total()))
 	}
 
-	logDiff := func(resps [][]byte) {
-		if len(resps) >= 2 {
-			diff, err := jsonpatch.CreateMergePatch(resps[1], resps[0])
-			if err == nil {
-				log.Warn(fmt.Sprintf("The first two responses have following diff (resp2 -> resp1):\n%s", string(diff)))
-			}
-		}
+	type candidateInfo struct {
+		idx      int
+		b        []byte
+		instance *swagger.Property
 	}
+	var candidates []candidateInfo
 
-	if ov == nil || ov.ResponseSelectorMerge == "" && ov.ResponseSelectorJSON == "" {
-		log.Warn(fmt.Sprintf("select the 1st response from %d", len(resps)))
-		b1, err := json.Marshal(resps[0])
+	// The 2nd candidate is only used to log the diff, there is no need to enumerate further.
+	for idx := 0; idx < mono.Len() && len(candidates) < 2; idx++ {
+		modelInstance := mono.Instance(idx)
+		syn, err := swagger.NewSynthesizer(&modelInstance, rnd, synthOpt)
+		if err != nil {
+			return 0, 0, nil, nil, nil, err
+		}
+		sv, ok := syn.Synthesize()
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(sv)
 		if err != nil {
-			return 0, nil, err
+			return 0, 0, nil, nil, nil, err
 		}
-		if b2, err := json.Marshal(resps[1]); err == nil {
-			logDiff([][]byte{b1, b2})
+		ok, err = matchResponseSelector(b, ov)
+		if err != nil {
+			return 0, 0, nil, nil, nil, err
+		}
+		if ok {
+			candidates = append(candidates, candidateInfo{
+				idx:      idx,
+				b:        b,
+				instance: &modelInstance,
+			})
 		}
-		return 0, b1, nil
 	}
 
-	selector := ov.ResponseSelectorMerge
-	if selector == "" {
-		selector = ov.ResponseSelectorJSON
-	}
+	hasSelector := ov != nil && ov.ResponseSelectorMerge+ov.ResponseSelectorJSON != ""
 
-	type candidateInfo struct {
-		idx int
-		b   []byte
+	if len(candidates) == 0 {
+		if hasSelector {
+			if mono.Total() > mono.Len() {
+				return 0, 0, nil, nil, nil, fmt.Errorf("no synth response found with the response selector among the first %d of %d instances, which are capped by the override's `max_instances` (defaults to %d): %s", mono.Len(), mono.Total(), DefaultMaxInstances, ov.ResponseSelectorMerge+ov.ResponseSelectorJSON)
+			}
+			return 0, 0, nil, nil, nil, fmt.Errorf("no synth response found with the response selector among %d instances: %s", mono.Len(), ov.ResponseSelectorMerge+ov.ResponseSelectorJSON)
+		}
+		return 0, 0, nil, nil, nil, fmt.Errorf("no responses to select")
 	}
 
-	var candidates []candidateInfo
-	for idx, resp := range resps {
-		bOld, err := json.Marshal(resp)
-		if err != nil {
-			return 0, nil, err
+	if len(candidates) > 1 {
+		if hasSelector {
+			log.Warn(fmt.Sprintf("select the 1st response from multiple candidates of %d instances (after selection)", mono.Total()))
+		} else {
+			log.Warn(fmt.Sprintf("select the 1st response from %d instances", mono.Total()))
 		}
+		diff, err := jsonpatch.CreateMergePatch(candidates[1].b, candidates[0].b)
+		if err == nil {
+			log.Warn(fmt.Sprintf("The first two responses have following diff (resp2 -> resp1):\n%s", string(diff)))
+		}
+	}
 
-		log.Debug("override", "type", "selector", "sel", selector, "resp", string(bOld))
+	return candidates[0].idx, mono.Total(), candidates[0].b, exp.Root(), candidates[0].instance, nil
+}
 
-		// Each selector is a json merge patch, we expect to apply this patch to the response and pick
-		// the one that has no difference between the itself and with the patch applied.
+// matchResponseSelector tells whether the response matches the response selector of the override (if any).
+func matchResponseSelector(resp []byte, ov *Override) (bool, error) {
+	if ov == nil || ov.ResponseSelectorMerge == "" && ov.ResponseSelectorJSON == "" {
+		return true, nil
+	}
 
-		var bNew []byte
-		if ov.ResponseSelectorMerge != "" {
-			bNew, err = jsonpatch.MergePatch(bOld, []byte(ov.ResponseSelectorMerge))
-		} else {
-			patch, err := jsonpatch.DecodePatch([]byte(ov.ResponseSelectorJSON))
-			if err != nil {
-				return 0, nil, fmt.Errorf("decoding response selector json patch: %v", err)
-			}
-			bNew, err = patch.Apply(bOld)
-		}
+	log.Debug("override", "type", "selector", "sel", ov.ResponseSelectorMerge+ov.ResponseSelectorJSON, "resp", string(resp))
+
+	// Each selector is a json merge patch, we expect to apply this patch to the response and pick
+	// the one that has no difference between the itself and with the patch applied.
+	var (
+		nresp []byte
+		err   error
+	)
+	if ov.ResponseSelectorMerge != "" {
+		nresp, err = jsonpatch.MergePatch(resp, []byte(ov.ResponseSelectorMerge))
+	} else {
+		var patch jsonpatch.Patch
+		patch, err = jsonpatch.DecodePatch([]byte(ov.ResponseSelectorJSON))
 		if err != nil {
-			return 0, nil, fmt.Errorf("applying response selector patch: %v", err)
+			return false, fmt.Errorf("decoding response selector json patch: %v", err)
 		}
-
-		if string(bOld) == string(bNew) {
-			candidates = append(candidates, candidateInfo{
-				idx: idx,
-				b:   bOld,
-			})
+		nresp, err = patch.Apply(resp)
+		if err != nil {
+			// E.g. the "test" operation fails, which means the response is not matched.
+			log.Debug("override", "type", "selector", "mismatch", err.Error())
+			return false, nil
 		}
 	}
-
-	if len(candidates) == 0 {
-		return 0, nil, fmt.Errorf("no synth response found with the response selector: %s", selector)
+	if err != nil {
+		return false, fmt.Errorf("applying response selector patch: %v", err)
 	}
+	return string(resp) == string(nresp), nil
+}
 
-	if len(candidates) > 1 {
-		log.Warn(fmt.Sprintf("select the 1st response from %d (after selection)", len(candidates)))
-		logDiff([][]byte{candidates[0].b, candidates[1].b})
-		return candidates[0].idx, candidates[0].b, nil
+// responseSelectorValue returns the response selector of the override as a JSON value, which is used to prune the monomorphized instances.
+// For the JSON patch selector, the value is built from the paths and values of its "test", "add" and "replace" operations.
+func responseSelectorValue(ov *Override) (interface{}, error) {
+	switch {
+	case ov == nil:
+		return nil, nil
+	case ov.ResponseSelectorMerge != "":
+		var v interface{}
+		if err := json.Unmarshal([]byte(ov.ResponseSelectorMerge), &v); err != nil {
+			return nil, fmt.Errorf("unmarshalling response selector merge patch: %v", err)
+		}
+		return v, nil
+	case ov.ResponseSelectorJSON != "":
+		var ops []struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(ov.ResponseSelectorJSON), &ops); err != nil {
+			return nil, fmt.Errorf("unmarshalling response selector json patch: %v", err)
+		}
+		unescaper := strings.NewReplacer("~1", "/", "~0", "~")
+		root := map[string]interface{}{}
+		for _, op := range ops {
+			if op.Op != "test" && op.Op != "add" && op.Op != "replace" || op.Path == "" {
+				continue
+			}
+			tokens := strings.Split(op.Path, "/")[1:]
+			m := root
+			for i, token := range tokens {
+				token = unescaper.Replace(token)
+				if i == len(tokens)-1 {
+					m[token] = op.Value
+					break
+				}
+				next, ok := m[token].(map[string]interface{})
+				if !ok {
+					next = map[string]interface{}{}
+					m[token] = next
+				}
+				m = next
+			}
+		}
+		return root, nil
 	}
-
-	return candidates[0].idx, candidates[0].b, nil
+	return nil, nil
 }
 
 func (srv *Server) handleToken(w http.ResponseWriter, r *http.Request) {
@@ -463,28 +696,77 @@ func (srv *Server) Stop(ctx context.Context) error {
 	return nil
 }
 
-// InitExecution initiates for each execution, for resetting the overrides and the rnd.
+// InitExecution initiates for each execution, for resetting the overrides and the records.
 func (srv *Server) InitExecution(ov []Override) {
+	srv.mu.Lock()
 	srv.overrides = ov
+	srv.mu.Unlock()
 	srv.InitVibration(nil)
 }
 
 func (srv *Server) InitVibration(vibrate *Vibration) {
-	srv.records = nil
-	srv.rnd = swagger.NewRnd(nil)
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.occurrences = map[string]int{}
+	srv.records = map[requestKey]record{}
 	srv.vibration = vibrate
 	srv.vibrationRecord = nil
-	srv.seqs = nil
+	srv.vibrationPath = ""
+	srv.seqs = map[requestKey]MonoModelDesc{}
+}
+
+// sortedRequestKeys returns the keys of the map ordered by the request identity.
+func sortedRequestKeys[T any](m map[requestKey]T) []requestKey {
+	keys := make([]requestKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
 }
 
+// Records returns the JSON values of the recorded responses, ordered by the request identity.
 func (srv *Server) Records() []swagger.JSONValue {
-	return srv.records
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var out []swagger.JSONValue
+	for _, key := range sortedRequestKeys(srv.records) {
+		out = append(out, srv.records[key].value)
+	}
+	return out
+}
+
+// Models returns the expanded response models of the recorded responses, in the same order as Records.
+func (srv *Server) Models() []*swagger.Property {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var out []*swagger.Property
+	for _, key := range sortedRequestKeys(srv.records) {
+		out = append(out, srv.records[key].model)
+	}
+	return out
+}
+
+// VibrationPath returns the JSON pointer to the vibrated value within the VibrationRecord.
+func (srv *Server) VibrationPath() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.vibrationPath
 }
 
 func (srv *Server) VibrationRecord() *swagger.JSONValue {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
 	return srv.vibrationRecord
 }
 
+// Sequences returns the monomorphized models of the handled requests, ordered by the request identity.
 func (srv *Server) Sequences() []MonoModelDesc {
-	return srv.seqs
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var out []MonoModelDesc
+	for _, key := range sortedRequestKeys(srv.seqs) {
+		out = append(out, srv.seqs[key])
+	}
+	return out
 }
@@ -0,0 +1,376 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger"
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *Server {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	return &Server{
+		Idx: azidx.Index{
+			ResourceProviders: azidx.ResourceProviders{
+				"MICROSOFT.FOO": azidx.APIVersions{
+					"2023-01-01": azidx.APIMethods{
+						"GET": azidx.ResourceTypes{
+							"/FOOS": &azidx.OperationInfo{
+								OperationRefs: azidx.OperationRefs{
+									"/SUBSCRIPTIONS/{}/RESOURCEGROUPS/{}/PROVIDERS/MICROSOFT.FOO/FOOS/{}": jsonreference.MustCreateRef("foo.json#/paths/~1subscriptions~1{subscriptionId}~1resourceGroups~1{resourceGroupName}~1providers~1Microsoft.Foo~1foos~1{fooName}/get"),
+								},
+							},
+							"/BARS": &azidx.OperationInfo{
+								OperationRefs: azidx.OperationRefs{
+									"/SUBSCRIPTIONS/{}/RESOURCEGROUPS/{}/PROVIDERS/MICROSOFT.FOO/BARS/{}": jsonreference.MustCreateRef("foo.json#/paths/~1subscriptions~1{subscriptionId}~1resourceGroups~1{resourceGroupName}~1providers~1Microsoft.Foo~1bars~1{barName}/get"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Specdir: filepath.Join(pwd, "testdata"),
+	}
+}
+
+func TestServerHandleConcurrently(t *testing.T) {
+	const n = 50
+
+	for _, cache := range []*swagger.ExpanderCache{nil, swagger.NewExpanderCache()} {
+		srv := newTestServer(t)
+		srv.InitExecution([]Override{
+			{
+				PathPattern:    *regexp.MustCompile(".*"),
+				SynthOption:    &swagger.SynthesizerOption{},
+				ExpanderOption: &swagger.ExpanderOption{Cache: cache},
+			},
+		})
+
+		var wg sync.WaitGroup
+		codes := make([]int, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Foo/foos/foo%d?api-version=2023-01-01", i), nil)
+				w := httptest.NewRecorder()
+				srv.Handle(w, req)
+				codes[i] = w.Code
+			}(i)
+		}
+		wg.Wait()
+
+		for i, code := range codes {
+			require.Equal(t, http.StatusOK, code, "request %d", i)
+		}
+		require.Len(t, srv.Records(), n)
+		require.Len(t, srv.Sequences(), n)
+		require.Len(t, srv.Models(), n)
+
+		// Each response is synthesized with unique values, as the rnd of each request is derived from its distinct identity.
+		ids := map[interface{}]bool{}
+		for _, record := range srv.Records() {
+			ids[record.JSONValue().(map[string]interface{})["id"]] = true
+		}
+		require.Len(t, ids, n)
+	}
+}
+
+func TestServerHandleConcurrentlyDeterministic(t *testing.T) {
+	const n = 50
+
+	srv := newTestServer(t)
+	srv.InitExecution([]Override{
+		{
+			PathPattern: *regexp.MustCompile(".*"),
+			SynthOption: &swagger.SynthesizerOption{},
+		},
+	})
+
+	run := func() ([]interface{}, []MonoModelDesc) {
+		srv.InitVibration(nil)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				// Each path is requested multiple times, which are told apart by their occurrences.
+				req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Foo/foos/foo%d?api-version=2023-01-01", i%10), nil)
+				srv.Handle(httptest.NewRecorder(), req)
+			}(i)
+		}
+		wg.Wait()
+		var values []interface{}
+		for _, record := range srv.Records() {
+			values = append(values, record.JSONValue())
+		}
+		return values, srv.Sequences()
+	}
+
+	values, seqs := run()
+	require.Len(t, values, n)
+	ids := map[interface{}]bool{}
+	for _, v := range values {
+		ids[v.(map[string]interface{})["id"]] = true
+	}
+	require.Len(t, ids, n)
+
+	for i := 0; i < 3; i++ {
+		nvalues, nseqs := run()
+		require.Equal(t, values, nvalues)
+		require.Equal(t, seqs, nseqs)
+	}
+}
+
+func TestServerHandleWithResponseSelector(t *testing.T) {
+	cases := []struct {
+		name      string
+		ov        *Override
+		instances int
+		kind      string
+	}{
+		{
+			name:      "No selector",
+			instances: 2,
+			kind:      "Cat",
+		},
+		{
+			name: "Merge patch selector",
+			ov: &Override{
+				ResponseSelectorMerge: `{"properties": {"pet": {"kind": "Dog"}}}`,
+			},
+			instances: 1,
+			kind:      "Dog",
+		},
+		{
+			name: "JSON patch selector",
+			ov: &Override{
+				ResponseSelectorJSON: `[{"op": "test", "path": "/properties/pet/kind", "value": "Cat"}]`,
+			},
+			instances: 1,
+			kind:      "Cat",
+		},
+		{
+			name: "Variant selector",
+			ov: &Override{
+				ResponseSelectorVariant: []swagger.PropertyAddr{swagger.MustParseAddr("properties/pet{Dog}")},
+			},
+			instances: 1,
+			kind:      "Dog",
+		},
+		{
+			name: "Capped",
+			ov: &Override{
+				MaxInstances: 1,
+			},
+			instances: 2,
+			kind:      "Cat",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			var ovs []Override
+			if tt.ov != nil {
+				ov := *tt.ov
+				ov.PathPattern = *regexp.MustCompile(".*")
+				ovs = append(ovs, ov)
+			}
+			srv.InitExecution(ovs)
+
+			req := httptest.NewRequest(http.MethodGet, "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Foo/foos/foo?api-version=2023-01-01", nil)
+			w := httptest.NewRecorder()
+			srv.Handle(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			seqs := srv.Sequences()
+			require.Len(t, seqs, 1)
+			require.Equal(t, tt.instances, seqs[0].Instances)
+
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tt.kind, resp["properties"].(map[string]interface{})["pet"].(map[string]interface{})["kind"])
+		})
+	}
+}
+
+func TestServerHandleRecordsServedCompositionVariant(t *testing.T) {
+	for _, label := range []string{"0", "1"} {
+		t.Run(label, func(t *testing.T) {
+			srv := newTestServer(t)
+			srv.InitExecution([]Override{
+				{
+					PathPattern:             *regexp.MustCompile(".*"),
+					ResponseSelectorVariant: []swagger.PropertyAddr{swagger.MustParseAddr("choice{" + label + "}")},
+				},
+			})
+			req := httptest.NewRequest(http.MethodGet, "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Foo/bars/bar?api-version=2023-01-01", nil)
+			w := httptest.NewRecorder()
+			srv.Handle(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			records := srv.Records()
+			require.Len(t, records, 1)
+			pos, err := swagger.JSONValuePosByPointer(records[0], "/choice")
+			require.NoError(t, err)
+			require.NotNil(t, pos)
+			require.Equal(t, "choice{"+label+"}", pos.Addr.String())
+		})
+	}
+}
+
+func TestServerHandleWithResponseSelectorCapped(t *testing.T) {
+	srv := newTestServer(t)
+	srv.InitExecution([]Override{
+		{
+			PathPattern:          *regexp.MustCompile(".*"),
+			ResponseSelectorJSON: `[{"op": "test", "path": "/name", "value": "not exist"}]`,
+			MaxInstances:         1,
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Foo/foos/foo?api-version=2023-01-01", nil)
+	w := httptest.NewRecorder()
+	srv.Handle(w, req)
+	require.NotEqual(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "first 1 of 2 instances")
+	require.Contains(t, w.Body.String(), "`max_instances`")
+}
+
+func TestServerHandleWithVibration(t *testing.T) {
+	cases := []struct {
+		name      string
+		vibration Vibration
+		verify    func(t *testing.T, resp map[string]interface{})
+		addr      string
+	}{
+		{
+			name: "replace",
+			vibration: Vibration{
+				Path:  "/name",
+				Value: "vibrated",
+			},
+			verify: func(t *testing.T, resp map[string]interface{}) {
+				require.Equal(t, "vibrated", resp["name"])
+			},
+			addr: "name",
+		},
+		{
+			name: "remove",
+			vibration: Vibration{
+				Path: "/properties/pet",
+				Op:   VibrationOpRemove,
+			},
+			verify: func(t *testing.T, resp map[string]interface{}) {
+				require.NotContains(t, resp["properties"], "pet")
+			},
+			addr: "properties/pet{Cat}",
+		},
+		{
+			name: "add",
+			vibration: Vibration{
+				Path:  "/properties/rules",
+				Op:    VibrationOpAdd,
+				Value: []interface{}{"x", "y", "z"},
+			},
+			verify: func(t *testing.T, resp map[string]interface{}) {
+				require.Equal(t, []interface{}{"x", "y", "z"}, resp["properties"].(map[string]interface{})["rules"])
+			},
+			addr: "properties/rules",
+		},
+		{
+			name: "duplicate",
+			vibration: Vibration{
+				Path: "/properties/rules/0",
+				Op:   VibrationOpDuplicate,
+			},
+			verify: func(t *testing.T, resp map[string]interface{}) {
+				rules := resp["properties"].(map[string]interface{})["rules"].([]interface{})
+				require.Len(t, rules, 2)
+				require.Equal(t, rules[0], rules[1])
+			},
+			addr: "properties/rules/*",
+		},
+		{
+			name: "replace by address",
+			vibration: Vibration{
+				Addr:  ptr(swagger.MustParseAddr("properties/pet{Cat}/meow")),
+				Value: "vibrated",
+			},
+			verify: func(t *testing.T, resp map[string]interface{}) {
+				require.Equal(t, "vibrated", resp["properties"].(map[string]interface{})["pet"].(map[string]interface{})["meow"])
+			},
+			addr: "properties/pet{Cat}/meow",
+		},
+		{
+			name: "duplicate by address",
+			vibration: Vibration{
+				Addr: ptr(swagger.MustParseAddr("properties/rules/*[0]")),
+				Op:   VibrationOpDuplicate,
+			},
+			verify: func(t *testing.T, resp map[string]interface{}) {
+				require.Len(t, resp["properties"].(map[string]interface{})["rules"], 2)
+			},
+			addr: "properties/rules/*",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			srv.InitExecution(nil)
+			vibration := tt.vibration
+			vibration.PathPattern = *regexp.MustCompile(".*")
+			srv.InitVibration(&vibration)
+
+			req := httptest.NewRequest(http.MethodGet, "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Foo/foos/foo?api-version=2023-01-01", nil)
+			w := httptest.NewRecorder()
+			srv.Handle(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			tt.verify(t, resp)
+
+			record := srv.VibrationRecord()
+			require.NotNil(t, record)
+			pos, err := swagger.JSONValuePosByPointer(*record, srv.VibrationPath())
+			require.NoError(t, err)
+			require.NotNil(t, pos)
+			require.Equal(t, tt.addr, pos.Addr.String())
+		})
+	}
+}
+
+func TestServerHandleWithVibrationMissingDiscriminator(t *testing.T) {
+	srv := newTestServer(t)
+	srv.InitExecution(nil)
+	srv.InitVibration(&Vibration{
+		PathPattern: *regexp.MustCompile(".*"),
+		Path:        "/properties/pet",
+		Op:          VibrationOpAdd,
+		Value:       map[string]interface{}{"bark": "x"},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Foo/foos/foo?api-version=2023-01-01", nil)
+	w := httptest.NewRecorder()
+	srv.Handle(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), `misses the discriminator \"kind\"`)
+	require.Nil(t, srv.VibrationRecord())
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -1,7 +1,5 @@
 package swagger
 
-import "sort"
-
 func CatesianProduct[T any](params ...[]T) [][]T {
 	if params == nil {
 		return nil
@@ -28,38 +26,3 @@ func CatesianProduct[T any](params ...[]T) [][]T {
 	}
 	return result
 }
-
-func CatesianProductMap[T any](params map[string][]T) []map[string]T {
-	if params == nil {
-		return nil
-	}
-	result := []map[string]T{}
-	keys := make([]string, 0, len(params))
-	for k := range params {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	for _, k := range keys {
-		param := params[k]
-		if len(param) != 0 {
-			newresult := []map[string]T{}
-			for _, v := range param {
-				if len(result) == 0 {
-					res := map[string]T{k: v}
-					newresult = append(newresult, res)
-				} else {
-					for _, res := range result {
-						nres := map[string]T{}
-						for kk, vv := range res {
-							nres[kk] = vv
-						}
-						nres[k] = v
-						newresult = append(newresult, nres)
-					}
-				}
-			}
-			result = newresult
-		}
-	}
-	return result
-}
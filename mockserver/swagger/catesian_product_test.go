@@ -64,64 +64,3 @@ func TestCatesianProduct(t *testing.T) {
 		})
 	}
 }
-
-func TestCatesianProductMap(t *testing.T) {
-	cases := []struct {
-		name   string
-		params map[string][]interface{}
-		expect []map[string]interface{}
-	}{
-		{
-			name:   "nil",
-			params: nil,
-			expect: nil,
-		},
-		{
-			name:   "empty",
-			params: map[string][]interface{}{},
-			expect: []map[string]interface{}{},
-		},
-		{
-			name:   "{a: []}",
-			params: map[string][]interface{}{"a": {}},
-			expect: []map[string]interface{}{},
-		},
-		{
-			name:   "{a: [1]}",
-			params: map[string][]interface{}{"a": {1}},
-			expect: []map[string]interface{}{{"a": 1}},
-		},
-		{
-			name:   "{a: [1, 2]}",
-			params: map[string][]interface{}{"a": {1, 2}},
-			expect: []map[string]interface{}{{"a": 1}, {"a": 2}},
-		},
-		{
-			name:   "{a: [1], b: []}",
-			params: map[string][]interface{}{"a": {1}, "b": {}},
-			expect: []map[string]interface{}{{"a": 1}},
-		},
-		{
-			name:   "{a: [1, 2]} x {b: [3]}",
-			params: map[string][]interface{}{"a": {1, 2}, "b": {3}},
-			expect: []map[string]interface{}{{"a": 1, "b": 3}, {"a": 2, "b": 3}},
-		},
-		{
-			name:   "{a: [1, 2]} x {b: [3]} x {c: [40, 50]}",
-			params: map[string][]interface{}{"a": {1, 2}, "b": {3}, "c": {40, 50}},
-			expect: []map[string]interface{}{
-				{"a": 1, "b": 3, "c": 40},
-				{"a": 2, "b": 3, "c": 40},
-				{"a": 1, "b": 3, "c": 50},
-				{"a": 2, "b": 3, "c": 50},
-			},
-		},
-	}
-
-	for _, tt := range cases {
-		t.Run(tt.name, func(t *testing.T) {
-			result := CatesianProductMap(tt.params)
-			require.Equal(t, tt.expect, result)
-		})
-	}
-}
@@ -3,10 +3,11 @@ package swagger
 import (
 	"fmt"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/go-openapi/loads"
 	"github.com/go-openapi/spec"
 	"github.com/magodo/azure-rest-api-bridge/log"
 	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger/refutil"
@@ -15,30 +16,62 @@ import (
 type Expander struct {
 	root *Property
 
-	// The key represents each swagger spec
-	variantMaps map[string]VariantMap
-
 	// Regard empty object type (no properties&allOf&additionalProperties) as of string type
 	// This is for some poorly defined Swagger that defines property as empty objects, but actually return strings (e.g. Azure data factory RP).
 	emptyObjAsStr bool
 
 	// Once specified, it will be used for expanding the property. If no hit, it will also update the cache accordingly.
 	cache *ExpanderCache
+
+	// The maximum times that a ref can appear along the way to a property, which bounds the expansion of the recursive models.
+	recursionDepth int
+
+	// The per ref recursion depth that overrides the recursionDepth, keyed by the normalized ref.
+	refRecursionDepths map[string]int
+
+	// The spec (e.g. the one defines the operation) whose reachable specs are looked up for the variants of the polymorphic models.
+	specPath   string
+	variantMap VariantMap
 }
 
 type ExpanderOption struct {
 	EmptyObjAsStr bool
 	Cache         *ExpanderCache
+
+	// RecursionDepth specifies how many levels a recursive model is expanded. Default is 1, which means the expansion stops at the first circular reference.
+	RecursionDepth int
+	// RefRecursionDepths specifies the recursion depth for the specific refs (normalized), which overrides the RecursionDepth.
+	RefRecursionDepths map[string]int
 }
 
 // NewExpander create a expander for the schema referenced by the input json reference.
 // The reference must be a normalized reference.
 func NewExpander(ref spec.Ref, opt *ExpanderOption) (*Expander, error) {
+	return newExpander(ref, ref.GetURL().Path, opt)
+}
+
+// newExpander create a expander for the schema referenced by the input json reference, where the variants of the polymorphic models
+// are looked up from the specs that are reachable from the specPath.
+func newExpander(ref spec.Ref, specPath string, opt *ExpanderOption) (*Expander, error) {
 	if opt == nil {
 		opt = &ExpanderOption{}
 	}
 
-	psch, ownRef, visited, ok, err := refutil.RResolve(ref, nil, true)
+	e := &Expander{
+		emptyObjAsStr:      opt.EmptyObjAsStr,
+		cache:              opt.Cache,
+		recursionDepth:     opt.RecursionDepth,
+		refRecursionDepths: opt.RefRecursionDepths,
+		specPath:           specPath,
+	}
+
+	vm, err := loadVariantMap(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("building variant map for %s: %v", specPath, err)
+	}
+	e.variantMap = vm
+
+	psch, ownRef, visited, ok, err := refutil.RResolveBounded(ref, nil, true, e.depth)
 	if err != nil {
 		return nil, fmt.Errorf("recursively resolve schema %s: %v", &ref, err)
 	}
@@ -46,17 +79,14 @@ func NewExpander(ref spec.Ref, opt *ExpanderOption) (*Expander, error) {
 		return nil, fmt.Errorf("circular ref found when resolving schema: %s", &ref)
 	}
 
-	return &Expander{
-		root: &Property{
-			Schema:      psch,
-			ref:         ownRef,
-			addr:        RootAddr,
-			visitedRefs: visited,
-		},
-		variantMaps:   map[string]VariantMap{},
-		emptyObjAsStr: opt.EmptyObjAsStr,
-		cache:         opt.Cache,
-	}, nil
+	e.root = &Property{
+		Schema:      psch,
+		ref:         ownRef,
+		addr:        RootAddr,
+		visitedRefs: visited,
+		Meta:        PropertyMeta{}.overlay(psch),
+	}
+	return e, nil
 }
 
 // NewExpanderFromOpRef create a expander for the successful response schema of an operation referenced by the input json reference.
@@ -78,7 +108,7 @@ func NewExpanderFromOpRef(ref spec.Ref, opt *ExpanderOption) (*Expander, error)
 		return nil, fmt.Errorf("resolving path item ref %s: %v", &piref, err)
 	}
 
-	doc, err := loads.Spec(ref.GetURL().Path)
+	doc, err := loadSpec(ref.GetURL().Path)
 	if err != nil {
 		return nil, fmt.Errorf("loading the spec %s: %v", ref.GetURL().Path, err)
 	}
@@ -115,7 +145,7 @@ func NewExpanderFromOpRef(ref spec.Ref, opt *ExpanderOption) (*Expander, error)
 		return nil, fmt.Errorf("circular ref found when resolving response ref %s", &respref)
 	}
 
-	exp, err := NewExpander(refutil.Append(respref, "schema"), opt)
+	exp, err := newExpander(refutil.Append(respref, "schema"), ref.GetURL().Path, opt)
 	if err != nil {
 		return nil, err
 	}
@@ -177,9 +207,34 @@ func (e *Expander) expandPropStep(prop *Property) error {
 		return nil
 	}
 	if len(prop.Schema.Type) > 1 {
-		return fmt.Errorf("%s: type of property type is an array (not supported yet)", prop.addr)
+		var types []string
+		for _, t := range prop.Schema.Type {
+			if t != "null" {
+				types = append(types, t)
+			}
+		}
+		if len(types) > 1 {
+			log.Trace("expand step", "type", "multi-type", "prop", prop.addr.String(), "ref", prop.ref.String())
+			return e.expandPropAsMultiType(prop, types)
+		}
+		if len(types) == 0 {
+			// A null-only type is a null leaf
+			types = []string{"null"}
+		}
+		// The schema might be shared, copy it before modification
+		schema := *prop.Schema
+		schema.Type = types
+		prop.Schema = &schema
 	}
 	schema := prop.Schema
+	if len(schema.OneOf) != 0 {
+		log.Trace("expand step", "type", "oneOf", "prop", prop.addr.String(), "ref", prop.ref.String())
+		return e.expandPropAsComposition(prop, PropertyVariantKindOneOf)
+	}
+	if len(schema.AnyOf) != 0 {
+		log.Trace("expand step", "type", "anyOf", "prop", prop.addr.String(), "ref", prop.ref.String())
+		return e.expandPropAsComposition(prop, PropertyVariantKindAnyOf)
+	}
 	t := "object"
 	if len(schema.Type) == 1 {
 		t = schema.Type[0]
@@ -210,7 +265,7 @@ func (e *Expander) expandPropStepAsArray(prop *Property) error {
 	if schema.Items.Schema == nil {
 		return fmt.Errorf("%s: items of property is not a single schema (not supported yet)", addr)
 	}
-	schema, ownRef, visited, ok, err := refutil.RResolve(refutil.Append(prop.ref, "items"), prop.visitedRefs, false)
+	schema, ownRef, visited, ok, err := refutil.RResolveBounded(refutil.Append(prop.ref, "items"), prop.visitedRefs, false, e.depth)
 	if err != nil {
 		return fmt.Errorf("%s: recursively resolving items: %v", addr, err)
 	}
@@ -222,7 +277,9 @@ func (e *Expander) expandPropStepAsArray(prop *Property) error {
 		RootModel:   prop.RootModel,
 		ref:         ownRef,
 		addr:        addr,
+		clientAddr:  clientAddrAppend(prop, addr[len(addr)-1]),
 		visitedRefs: visited,
+		Meta:        PropertyMeta{}.overlay(schema),
 	}
 	return nil
 }
@@ -252,12 +309,13 @@ func (e *Expander) expandPropAsMap(prop *Property) error {
 			RootModel:   prop.RootModel,
 			ref:         refutil.Append(prop.ref, "additionalProperties"),
 			addr:        addr,
+			clientAddr:  clientAddrAppend(prop, addr[len(addr)-1]),
 			visitedRefs: prop.visitedRefs,
 		}
 		return nil
 	}
 
-	schema, ownRef, visited, ok, err := refutil.RResolve(refutil.Append(prop.ref, "additionalProperties"), prop.visitedRefs, false)
+	schema, ownRef, visited, ok, err := refutil.RResolveBounded(refutil.Append(prop.ref, "additionalProperties"), prop.visitedRefs, false, e.depth)
 	if err != nil {
 		return fmt.Errorf("%s: recursively resolving additionalProperties: %v", addr, err)
 	}
@@ -279,7 +337,9 @@ func (e *Expander) expandPropAsMap(prop *Property) error {
 		Schema:      schema,
 		ref:         ownRef,
 		addr:        addr,
+		clientAddr:  clientAddrAppend(prop, addr[len(addr)-1]),
 		visitedRefs: visited,
+		Meta:        PropertyMeta{}.overlay(schema),
 	}
 	return nil
 }
@@ -290,7 +350,7 @@ func (e *Expander) expandPropAsObject(prop *Property) error {
 		return fmt.Errorf("%s: is not object", prop.addr)
 	}
 
-	if SchemaIsEmptyObject(schema) && e.emptyObjAsStr {
+	if SchemaIsEmptyObject(schema) && e.emptyObjAsStr && len(prop.Children) == 0 {
 		//schema.Type = []string{"string"}
 		*schema = spec.Schema{
 			SchemaProps: spec.SchemaProps{
@@ -306,11 +366,13 @@ func (e *Expander) expandPropAsObject(prop *Property) error {
 	// - Leaf polymorphic model
 	// Especially, if the current property is expanded as a variant, we will always expand it as a regular object, no matter that variant model is still a polymorphic object.
 	// Since we will expand all of its (cascaded) variants at its parent level.
-	vm, err := e.initVariantMap(prop.ref.GetURL().Path)
-	if err != nil {
-		return err
+	var (
+		varInfo *VariantInfo
+		ok      bool
+	)
+	if prop.SchemaName() != "" {
+		varInfo, ok = e.variantMap.Get(prop.ref.String())
 	}
-	varInfo, ok := vm.Get(prop.SchemaName())
 	if !ok || len(varInfo.VariantValueToModel) == 0 || prop.Discriminator != "" {
 		// A regualr object
 		log.Trace("expand step", "type", "regular object", "prop", prop.addr.String(), "ref", prop.ref.String())
@@ -329,7 +391,7 @@ func (e *Expander) expandPropAsRegularObject(prop *Property) error {
 		return fmt.Errorf("%s: is not object", prop.addr)
 	}
 
-	if SchemaIsEmptyObject(schema) && e.emptyObjAsStr {
+	if SchemaIsEmptyObject(schema) && e.emptyObjAsStr && len(prop.Children) == 0 {
 		//schema.Type = []string{"string"}
 		*schema = spec.Schema{
 			SchemaProps: spec.SchemaProps{
@@ -339,7 +401,10 @@ func (e *Expander) expandPropAsRegularObject(prop *Property) error {
 		return nil
 	}
 
-	prop.Children = map[string]*Property{}
+	// The children might have been populated by the base schema of a oneOf/anyOf composition
+	if prop.Children == nil {
+		prop.Children = map[string]*Property{}
+	}
 
 	// Expanding the regular properties
 	for k := range schema.Properties {
@@ -348,7 +413,7 @@ func (e *Expander) expandPropAsRegularObject(prop *Property) error {
 			Type:  PropertyAddrStepTypeProp,
 			Value: k,
 		})
-		schema, ownRef, visited, ok, err := refutil.RResolve(refutil.Append(prop.ref, "properties", k), prop.visitedRefs, false)
+		schema, ownRef, visited, ok, err := refutil.RResolveBounded(refutil.Append(prop.ref, "properties", k), prop.visitedRefs, false, e.depth)
 		if err != nil {
 			return fmt.Errorf("%s: recursively resolving property %s: %v", addr, k, err)
 		}
@@ -360,13 +425,15 @@ func (e *Expander) expandPropAsRegularObject(prop *Property) error {
 			RootModel:   prop.RootModel,
 			ref:         ownRef,
 			addr:        addr,
+			clientAddr:  clientAddrOfChild(prop, k, prop.Schema.Properties[k]),
 			visitedRefs: visited,
+			Meta:        childMeta(prop.Schema, k, schema),
 		}
 	}
 
 	// Inheriting the allOf schemas
 	for i := range schema.AllOf {
-		schema, ownRef, visited, ok, err := refutil.RResolve(refutil.Append(prop.ref, "allOf", strconv.Itoa(i)), prop.visitedRefs, false)
+		schema, ownRef, visited, ok, err := refutil.RResolveBounded(refutil.Append(prop.ref, "allOf", strconv.Itoa(i)), prop.visitedRefs, false, e.depth)
 		if err != nil {
 			return fmt.Errorf("%s: recursively resolving %d-th allOf schema: %v", prop.addr, i, err)
 		}
@@ -374,11 +441,16 @@ func (e *Expander) expandPropAsRegularObject(prop *Property) error {
 			continue
 		}
 		tmpExp := Expander{
+			recursionDepth:     e.recursionDepth,
+			refRecursionDepths: e.refRecursionDepths,
+			specPath:           e.specPath,
+			variantMap:         e.variantMap,
 			root: &Property{
 				Schema:      schema,
 				RootModel:   prop.RootModel,
 				ref:         ownRef,
 				addr:        prop.addr,
+				clientAddr:  prop.clientAddr,
 				visitedRefs: visited,
 			},
 		}
@@ -409,17 +481,19 @@ func (e *Expander) expandPropAsPolymorphicObject(prop *Property, varInfo Variant
 			lastAddr.Variant = vValue
 			addr[len(addr)-1] = lastAddr
 		}
-		visited := map[string]bool{}
+		visited := map[string]int{}
 		for k, v := range prop.visitedRefs {
-			// Remove the owning ref of the base schema from visited set in order to allow the later allOf inheritance.
+			// Uncount the owning ref of the base schema from visited set in order to allow the later allOf inheritance.
 			if k == prop.ref.String() {
-				continue
+				v--
+			}
+			if v > 0 {
+				visited[k] = v
 			}
-			visited[k] = v
 		}
 
-		vref := spec.MustCreateRef(prop.ref.GetURL().Path + "#/definitions/" + vName)
-		psch, ownRef, visited, ok, err := refutil.RResolve(vref, visited, true)
+		vref := spec.MustCreateRef(vName)
+		psch, ownRef, visited, ok, err := refutil.RResolveBounded(vref, visited, true, e.depth)
 		if err != nil {
 			return fmt.Errorf("%s: recursively resolving variant schema %q by variant value %q: %v", addr, vName, vValue, err)
 		}
@@ -431,7 +505,9 @@ func (e *Expander) expandPropAsPolymorphicObject(prop *Property, varInfo Variant
 			RootModel:          prop.RootModel,
 			ref:                ownRef,
 			addr:               addr,
+			clientAddr:         clientAddrWithVariant(prop, vValue),
 			visitedRefs:        visited,
+			Meta:               prop.Meta.overlay(psch),
 			Discriminator:      varInfo.Discriminator,
 			DiscriminatorValue: vValue,
 		}
@@ -440,25 +516,211 @@ func (e *Expander) expandPropAsPolymorphicObject(prop *Property, varInfo Variant
 	return nil
 }
 
-func (e *Expander) initVariantMap(path string) (VariantMap, error) {
-	if m := e.variantMaps[path]; m != nil {
-		return m, nil
+// expandPropAsMultiType expands the property whose schema has multiple (non-null) types, where each type is expanded as a variant.
+func (e *Expander) expandPropAsMultiType(prop *Property, types []string) error {
+	prop.VariantKind = PropertyVariantKindType
+	prop.Variant = map[string]*Property{}
+	for _, t := range types {
+		addr := append(PropertyAddr{}, prop.addr...)
+		if len(addr) == 0 {
+			addr = append(addr, PropertyAddrStep{Type: PropertyAddrStepTypeProp, Variant: t})
+		} else {
+			lastAddr := addr[len(addr)-1]
+			lastAddr.Variant = t
+			addr[len(addr)-1] = lastAddr
+		}
+		schema := *prop.Schema
+		schema.Type = spec.StringOrArray{t}
+		prop.Variant[t] = &Property{
+			Schema:      &schema,
+			RootModel:   prop.RootModel,
+			ref:         prop.ref,
+			addr:        addr,
+			clientAddr:  clientAddrWithVariant(prop, t),
+			visitedRefs: prop.visitedRefs,
+			Meta:        prop.Meta,
+		}
 	}
-	m, err := NewVariantMap(path)
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// expandPropAsComposition expands the property whose schema has oneOf/anyOf schemas, where each schema is expanded as a variant.
+// The variant is labelled by the title of its schema, or its index if the title is absent or duplicated.
+// The properties defined in the composing schema (beside the oneOf/anyOf) are inherited by each object variant.
+func (e *Expander) expandPropAsComposition(prop *Property, kind PropertyVariantKind) error {
+	field, schemas := "oneOf", prop.Schema.OneOf
+	if kind == PropertyVariantKindAnyOf {
+		field, schemas = "anyOf", prop.Schema.AnyOf
+	}
+
+	// The properties of the composing schema
+	var baseChildren map[string]*Property
+	if len(prop.Schema.Properties) != 0 || len(prop.Schema.AllOf) != 0 {
+		schema := *prop.Schema
+		schema.OneOf = nil
+		schema.AnyOf = nil
+		schema.Type = spec.StringOrArray{"object"}
+		tmpExp := Expander{
+			recursionDepth:     e.recursionDepth,
+			refRecursionDepths: e.refRecursionDepths,
+			specPath:           e.specPath,
+			variantMap:         e.variantMap,
+			root: &Property{
+				Schema:      &schema,
+				RootModel:   prop.RootModel,
+				ref:         prop.ref,
+				addr:        prop.addr,
+				clientAddr:  prop.clientAddr,
+				visitedRefs: prop.visitedRefs,
+			},
+		}
+		if err := tmpExp.expandPropAsRegularObject(tmpExp.root); err != nil {
+			return fmt.Errorf("%s: expanding the (temporary) %s base schema: %v", prop.addr, field, err)
+		}
+		baseChildren = tmpExp.root.Children
 	}
-	e.variantMaps[path] = m
-	return m, nil
+
+	type alternative struct {
+		index int
+		title string
+		prop  *Property
+	}
+	var alternatives []alternative
+	titleCnt := map[string]int{}
+	for i := range schemas {
+		schema, ownRef, visited, ok, err := refutil.RResolveBounded(refutil.Append(prop.ref, field, strconv.Itoa(i)), prop.visitedRefs, false, e.depth)
+		if err != nil {
+			return fmt.Errorf("%s: recursively resolving %d-th %s schema: %v", prop.addr, i, field, err)
+		}
+		if !ok {
+			continue
+		}
+		if schema.Title != "" {
+			titleCnt[schema.Title]++
+		}
+		alternatives = append(alternatives, alternative{
+			index: i,
+			title: schema.Title,
+			prop: &Property{
+				Schema:      schema,
+				RootModel:   prop.RootModel,
+				ref:         ownRef,
+				visitedRefs: visited,
+				Meta:        prop.Meta.overlay(schema),
+			},
+		})
+	}
+
+	prop.VariantKind = kind
+	prop.Variant = map[string]*Property{}
+	for _, alt := range alternatives {
+		label := alt.title
+		if label == "" || titleCnt[label] > 1 {
+			label = strconv.Itoa(alt.index)
+		}
+		addr := append(PropertyAddr{}, prop.addr...)
+		if len(addr) == 0 {
+			addr = append(addr, PropertyAddrStep{Type: PropertyAddrStepTypeProp, Variant: label})
+		} else {
+			lastAddr := addr[len(addr)-1]
+			lastAddr.Variant = label
+			addr[len(addr)-1] = lastAddr
+		}
+		alt.prop.addr = addr
+		alt.prop.clientAddr = clientAddrWithVariant(prop, label)
+		if len(baseChildren) != 0 && SchemaIsObject(alt.prop.Schema) {
+			alt.prop.Children = map[string]*Property{}
+			for k, child := range baseChildren {
+				child := *child
+				child.addr = append(addr.Copy(), child.addr[len(prop.addr):]...)
+				if child.clientAddr != nil || alt.prop.clientAddr != nil {
+					child.clientAddr = append(alt.prop.ClientAddr().Copy(), child.ClientAddr()[len(prop.ClientAddr()):]...)
+				}
+				alt.prop.Children[k] = &child
+			}
+		}
+		prop.Variant[label] = alt.prop
+	}
+	return nil
+}
+
+// childMeta returns the metadata of the property k of the parent schema, whose resolved schema is sch.
+// The metadata defined alongside the property's $ref takes precedence over the resolved one.
+func childMeta(parent *spec.Schema, k string, sch *spec.Schema) PropertyMeta {
+	def := parent.Properties[k]
+	return PropertyMeta{Required: slices.Contains(parent.Required, k)}.overlay(sch).overlay(&def)
+}
+
+// clientAddrOfChild returns the client address of the property k of the prop, whose (unresolved) definition is sch.
+// It returns nil if the client address is the same as the wire address.
+func clientAddrOfChild(prop *Property, k string, sch spec.Schema) PropertyAddr {
+	name := k
+	if v, ok := sch.Extensions.GetString("x-ms-client-name"); ok && v != "" {
+		name = v
+	}
+	flatten, _ := sch.Extensions.GetBool("x-ms-client-flatten")
+	if prop.clientAddr == nil && name == k && !flatten {
+		return nil
+	}
+	addr := prop.ClientAddr().Copy()
+	if flatten {
+		// The properties of a flattened property are lifted to its parent.
+		return addr
+	}
+	return append(addr, PropertyAddrStep{
+		Type:  PropertyAddrStepTypeProp,
+		Value: name,
+	})
+}
+
+// clientAddrAppend returns the client address of the prop appended with the step, or nil if the prop has no specific client address.
+func clientAddrAppend(prop *Property, step PropertyAddrStep) PropertyAddr {
+	if prop.clientAddr == nil {
+		return nil
+	}
+	return append(prop.clientAddr.Copy(), step)
+}
+
+// clientAddrWithVariant returns the client address of the variant of the prop, or nil if the prop has no specific client address.
+func clientAddrWithVariant(prop *Property, variant string) PropertyAddr {
+	if prop.clientAddr == nil {
+		return nil
+	}
+	addr := prop.clientAddr.Copy()
+	if len(addr) == 0 {
+		return append(addr, PropertyAddrStep{Type: PropertyAddrStepTypeProp, Variant: variant})
+	}
+	addr[len(addr)-1].Variant = variant
+	return addr
+}
+
+// depth returns the recursion depth of the ref.
+func (e *Expander) depth(ref string) int {
+	if d, ok := e.refRecursionDepths[ref]; ok && d > 0 {
+		return d
+	}
+	if e.recursionDepth > 0 {
+		return e.recursionDepth
+	}
+	return 1
 }
 
 func (e *Expander) cacheKey() string {
-	key := e.root.ref.String() + "|"
+	key := e.root.ref.String() + "|" + e.specPath + "|"
 	if e.emptyObjAsStr {
 		key += "1"
 	} else {
 		key += "0"
 	}
+	key += "|" + strconv.Itoa(e.recursionDepth)
+	refs := make([]string, 0, len(e.refRecursionDepths))
+	for ref := range e.refRecursionDepths {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	for _, ref := range refs {
+		key += "|" + ref + "=" + strconv.Itoa(e.refRecursionDepths[ref])
+	}
 	return key
 }
 
@@ -1,22 +1,341 @@
 package swagger
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/go-openapi/spec"
+	"github.com/magodo/azure-rest-api-bridge/log"
+)
+
+// ExpanderCache caches the expanded property trees. It is safe for concurrent use.
+// The cached property trees are shared, which must not be modified.
 type ExpanderCache struct {
-	m map[string]*Property
+	mu sync.Mutex
+	m  map[string]*Property
+
+	// The directory to persist the cache, if not empty.
+	dir string
 }
 
 func NewExpanderCache() *ExpanderCache {
 	return &ExpanderCache{m: map[string]*Property{}}
 }
 
+// NewPersistentExpanderCache creates an ExpanderCache that is additionally persisted in the dir, which can be shared across runs.
+// A persisted entry is invalidated once any of the spec files it depends on changes.
+func NewPersistentExpanderCache(dir string) (*ExpanderCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cache dir %s: %v", dir, err)
+	}
+	return &ExpanderCache{m: map[string]*Property{}, dir: dir}, nil
+}
+
 func (cache *ExpanderCache) save(exp *Expander) {
-	cache.m[exp.cacheKey()] = exp.root
+	key := exp.cacheKey()
+	cache.mu.Lock()
+	cache.m[key] = exp.root
+	cache.mu.Unlock()
+	if cache.dir == "" {
+		return
+	}
+	if err := cache.persist(key, exp); err != nil {
+		log.Warn("persisting expander cache", "key", key, "error", err)
+	}
 }
 
 func (cache *ExpanderCache) load(exp *Expander) bool {
-	prop, ok := cache.m[exp.cacheKey()]
+	key := exp.cacheKey()
+	cache.mu.Lock()
+	prop, ok := cache.m[key]
+	cache.mu.Unlock()
 	if !ok {
-		return false
+		if cache.dir == "" {
+			return false
+		}
+		var err error
+		prop, err = cache.restore(key)
+		if err != nil {
+			log.Warn("restoring expander cache", "key", key, "error", err)
+			return false
+		}
+		if prop == nil {
+			return false
+		}
+		cache.mu.Lock()
+		cache.m[key] = prop
+		cache.mu.Unlock()
 	}
 	exp.root = prop
 	return true
 }
+
+// cacheFileVersion is bumped whenever the on-disk format changes, so that the existing cache files are regarded as stale.
+const cacheFileVersion = 2
+
+// cacheFile is the on-disk format of an expander cache entry.
+type cacheFile struct {
+	Version int    `json:"version"`
+	Key     string `json:"key"`
+	// The content hash of each spec file that the entry depends on
+	Deps map[string]string `json:"deps"`
+	Root *cacheProperty    `json:"root"`
+}
+
+// cacheProperty is the serializable form of the Property.
+type cacheProperty struct {
+	Schema             *spec.Schema              `json:"schema,omitempty"`
+	RootModel          RootModelInfo             `json:"root_model"`
+	Addr               string                    `json:"addr"`
+	ClientAddr         *string                   `json:"client_addr,omitempty"`
+	VisitedRefs        map[string]int            `json:"visited_refs,omitempty"`
+	Ref                string                    `json:"ref,omitempty"`
+	Discriminator      string                    `json:"discriminator,omitempty"`
+	DiscriminatorValue string                    `json:"discriminator_value,omitempty"`
+	Meta               PropertyMeta              `json:"meta"`
+	Children           map[string]*cacheProperty `json:"children"`
+	Element            *cacheProperty            `json:"element,omitempty"`
+	Variant            map[string]*cacheProperty `json:"variant"`
+	VariantKind        PropertyVariantKind       `json:"variant_kind,omitempty"`
+}
+
+func (cache *ExpanderCache) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(cache.dir, hex.EncodeToString(h[:])+".json")
+}
+
+func (cache *ExpanderCache) persist(key string, exp *Expander) error {
+	root := exp.root
+	deps := map[string]string{}
+	// The variants might come from any of the specs reachable from the spec path.
+	files, err := loadSpecClosure(exp.specPath)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		deps[f] = ""
+	}
+	root.Walk(func(p *Property) bool {
+		files := []string{p.ref.GetURL().Path}
+		for ref := range p.visitedRefs {
+			ref := spec.MustCreateRef(ref)
+			files = append(files, ref.GetURL().Path)
+		}
+		for _, f := range files {
+			if _, ok := deps[f]; ok || f == "" {
+				continue
+			}
+			deps[f] = ""
+		}
+		return true
+	})
+	for f := range deps {
+		h, err := fileHash(f)
+		if err != nil {
+			return err
+		}
+		deps[f] = h
+	}
+
+	b, err := json.Marshal(cacheFile{
+		Version: cacheFileVersion,
+		Key:     key,
+		Deps:    deps,
+		Root:    toCacheProperty(root),
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling: %v", err)
+	}
+
+	// Write to a temp file and rename it to avoid partial written cache file
+	f, err := os.CreateTemp(cache.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), cache.path(key))
+}
+
+// restore restores the cached property from the disk. It returns nil if the cache doesn't exist or is stale.
+func (cache *ExpanderCache) restore(key string) (*Property, error) {
+	b, err := os.ReadFile(cache.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cf cacheFile
+	if err := json.Unmarshal(b, &cf); err != nil {
+		return nil, fmt.Errorf("unmarshalling: %v", err)
+	}
+	if cf.Version != cacheFileVersion || cf.Key != key {
+		return nil, nil
+	}
+	files := make([]string, 0, len(cf.Deps))
+	for f := range cf.Deps {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		h, err := fileHash(f)
+		if err != nil || h != cf.Deps[f] {
+			log.Debug("stale expander cache", "key", key, "file", f)
+			return nil, nil
+		}
+	}
+	return fromCacheProperty(cf.Root)
+}
+
+func fileHash(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+func toCacheProperty(p *Property) *cacheProperty {
+	if p == nil {
+		return nil
+	}
+	cp := &cacheProperty{
+		Schema:             shallowSchema(p.Schema),
+		RootModel:          p.RootModel,
+		Addr:               p.addr.String(),
+		VisitedRefs:        p.visitedRefs,
+		Ref:                p.ref.String(),
+		Discriminator:      p.Discriminator,
+		DiscriminatorValue: p.DiscriminatorValue,
+		Meta:               p.Meta,
+		Element:            toCacheProperty(p.Element),
+		VariantKind:        p.VariantKind,
+	}
+	if p.clientAddr != nil {
+		clientAddr := p.clientAddr.String()
+		cp.ClientAddr = &clientAddr
+	}
+	if p.Children != nil {
+		cp.Children = map[string]*cacheProperty{}
+		for k, v := range p.Children {
+			cp.Children[k] = toCacheProperty(v)
+		}
+	}
+	if p.Variant != nil {
+		cp.Variant = map[string]*cacheProperty{}
+		for k, v := range p.Variant {
+			cp.Variant[k] = toCacheProperty(v)
+		}
+	}
+	return cp
+}
+
+func fromCacheProperty(cp *cacheProperty) (*Property, error) {
+	if cp == nil {
+		return nil, nil
+	}
+	addr, err := ParseAddr(cp.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing address %q: %v", cp.Addr, err)
+	}
+	var ref spec.Ref
+	if cp.Ref != "" {
+		ref, err = spec.NewRef(cp.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ref %q: %v", cp.Ref, err)
+		}
+	}
+	p := &Property{
+		Schema:             cp.Schema,
+		RootModel:          cp.RootModel,
+		addr:               *addr,
+		visitedRefs:        cp.VisitedRefs,
+		ref:                ref,
+		Discriminator:      cp.Discriminator,
+		DiscriminatorValue: cp.DiscriminatorValue,
+		Meta:               cp.Meta,
+		VariantKind:        cp.VariantKind,
+	}
+	if cp.ClientAddr != nil {
+		clientAddr, err := ParseAddr(*cp.ClientAddr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing client address %q: %v", *cp.ClientAddr, err)
+		}
+		p.clientAddr = clientAddr.Copy()
+	}
+	if p.Element, err = fromCacheProperty(cp.Element); err != nil {
+		return nil, err
+	}
+	if cp.Children != nil {
+		p.Children = map[string]*Property{}
+		for k, v := range cp.Children {
+			if p.Children[k], err = fromCacheProperty(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if cp.Variant != nil {
+		p.Variant = map[string]*Property{}
+		for k, v := range cp.Variant {
+			if p.Variant[k], err = fromCacheProperty(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// shallowSchema returns a copy of the schema without the nested schemas, which are already expanded in the property tree.
+// The presence (and the keys) of the nested schemas are kept, as they are used to tell the kind of the schema (e.g. SchemaIsMap).
+func shallowSchema(schema *spec.Schema) *spec.Schema {
+	if schema == nil {
+		return nil
+	}
+	s := *schema
+	if s.Properties != nil {
+		s.Properties = spec.SchemaProperties{}
+		for k := range schema.Properties {
+			s.Properties[k] = spec.Schema{}
+		}
+	}
+	if s.Items != nil {
+		s.Items = &spec.SchemaOrArray{Schema: &spec.Schema{}}
+	}
+	if s.AdditionalProperties != nil {
+		s.AdditionalProperties = &spec.SchemaOrBool{Allows: schema.AdditionalProperties.Allows}
+		if schema.AdditionalProperties.Schema != nil {
+			s.AdditionalProperties.Schema = &spec.Schema{}
+		}
+	}
+	s.AllOf = emptySchemas(s.AllOf)
+	s.OneOf = emptySchemas(s.OneOf)
+	s.AnyOf = emptySchemas(s.AnyOf)
+	s.Not = nil
+	s.Definitions = nil
+	s.PatternProperties = nil
+	s.AdditionalItems = nil
+	s.Dependencies = nil
+	return &s
+}
+
+func emptySchemas(l []spec.Schema) []spec.Schema {
+	if l == nil {
+		return nil
+	}
+	return make([]spec.Schema, len(l))
+}
@@ -32,72 +32,72 @@ func TestExpand(t *testing.T) {
 				expect := &Property{
 					Schema: ptr(swg.Definitions["object"]),
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/object": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/object": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/object"),
 					Children: map[string]*Property{
 						"number": {
 							Schema: ptr(swg.Definitions["object"].Properties["number"]),
 							addr:   MustParseAddr("number"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/number"),
 						},
 						"integer": {
 							Schema: ptr(swg.Definitions["object"].Properties["integer"]),
 							addr:   MustParseAddr("integer"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/integer"),
 						},
 						"string": {
 							Schema: ptr(swg.Definitions["object"].Properties["string"]),
 							addr:   MustParseAddr("string"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/string"),
 						},
 						"boolean": {
 							Schema: ptr(swg.Definitions["object"].Properties["boolean"]),
 							addr:   MustParseAddr("boolean"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/boolean"),
 						},
 						"object": {
 							Schema: ptr(swg.Definitions["object"].Properties["object"]),
 							addr:   MustParseAddr("object"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/object"),
 							Children: map[string]*Property{
 								"p1": {
 									Schema: ptr(swg.Definitions["object"].Properties["object"].Properties["p1"]),
 									addr:   MustParseAddr("object/p1"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/object": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/object": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/object/properties/p1"),
 								},
 								"obj": {
 									Schema: ptr(swg.Definitions["object"].Properties["object"].Properties["obj"]),
 									addr:   MustParseAddr("object/obj"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/object": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/object": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/object/properties/obj"),
 									Children: map[string]*Property{
 										"pp1": {
 											Schema: ptr(swg.Definitions["object"].Properties["object"].Properties["obj"].Properties["pp1"]),
 											addr:   MustParseAddr("object/obj/pp1"),
-											visitedRefs: map[string]bool{
-												specpathA + "#/definitions/object": true,
+											visitedRefs: map[string]int{
+												specpathA + "#/definitions/object": 1,
 											},
 											ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/object/properties/obj/properties/pp1"),
 										},
@@ -108,8 +108,8 @@ func TestExpand(t *testing.T) {
 						"emptyObject": {
 							Schema: ptr(swg.Definitions["object"].Properties["emptyObject"]),
 							addr:   MustParseAddr("emptyObject"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref:      spec.MustCreateRef(specpathA + "#/definitions/object/properties/emptyObject"),
 							Children: map[string]*Property{},
@@ -117,15 +117,15 @@ func TestExpand(t *testing.T) {
 						"array": {
 							Schema: ptr(swg.Definitions["object"].Properties["array"]),
 							addr:   MustParseAddr("array"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/array"),
 							Element: &Property{
 								Schema: swg.Definitions["object"].Properties["array"].Items.Schema,
 								addr:   MustParseAddr("array/*"),
-								visitedRefs: map[string]bool{
-									specpathA + "#/definitions/object": true,
+								visitedRefs: map[string]int{
+									specpathA + "#/definitions/object": 1,
 								},
 								ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/array/items"),
 							},
@@ -133,15 +133,15 @@ func TestExpand(t *testing.T) {
 						"map": {
 							Schema: ptr(swg.Definitions["object"].Properties["map"]),
 							addr:   MustParseAddr("map"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/map"),
 							Element: &Property{
 								Schema: swg.Definitions["object"].Properties["map"].AdditionalProperties.Schema,
 								addr:   MustParseAddr("map/*"),
-								visitedRefs: map[string]bool{
-									specpathA + "#/definitions/object": true,
+								visitedRefs: map[string]int{
+									specpathA + "#/definitions/object": 1,
 								},
 								ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/map/additionalProperties"),
 							},
@@ -149,8 +149,8 @@ func TestExpand(t *testing.T) {
 						"map2": {
 							Schema: ptr(swg.Definitions["object"].Properties["map2"]),
 							addr:   MustParseAddr("map2"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/object": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/object": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/map2"),
 							Element: &Property{
@@ -160,8 +160,8 @@ func TestExpand(t *testing.T) {
 									},
 								},
 								addr: MustParseAddr("map2/*"),
-								visitedRefs: map[string]bool{
-									specpathA + "#/definitions/object": true,
+								visitedRefs: map[string]int{
+									specpathA + "#/definitions/object": 1,
 								},
 								ref: spec.MustCreateRef(specpathA + "#/definitions/object/properties/map2/additionalProperties"),
 							},
@@ -178,82 +178,87 @@ func TestExpand(t *testing.T) {
 				swg := swgs[0]
 				expect := &Property{
 					Schema: ptr(swg.Definitions["Pet"]),
+					Meta:   PropertyMeta{Description: "Pet"},
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/Pet": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/Pet": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/Pet"),
 					Variant: map[string]*Property{
 						"Dog": {
 							Schema:             ptr(swg.Definitions["Dog"]),
+							Meta:               PropertyMeta{Description: "Dog"},
 							Discriminator:      "type",
 							DiscriminatorValue: "Dog",
 							addr:               MustParseAddr("{Dog}"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/Dog": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/Dog": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/Dog"),
 							Children: map[string]*Property{
 								"type": {
 									Schema: ptr(swg.Definitions["Pet"].Properties["type"]),
+									Meta:   PropertyMeta{Enum: []interface{}{"Dog", "Cat"}},
 									addr:   MustParseAddr("{Dog}/type"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/Dog": true,
-										specpathA + "#/definitions/Pet": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/Dog": 1,
+										specpathA + "#/definitions/Pet": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/type"),
 								},
 								"nickname": {
 									Schema: ptr(swg.Definitions["Pet"].Properties["nickname"]),
 									addr:   MustParseAddr("{Dog}/nickname"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/Dog": true,
-										specpathA + "#/definitions/Pet": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/Dog": 1,
+										specpathA + "#/definitions/Pet": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/nickname"),
 								},
 								"cat_friends": {
 									Schema: ptr(swg.Definitions["Dog"].Properties["cat_friends"]),
 									addr:   MustParseAddr("{Dog}/cat_friends"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/Dog": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/Dog": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/Dog/properties/cat_friends"),
 									Element: &Property{
 										Schema: ptr(swg.Definitions["Cat"]),
+										Meta:   PropertyMeta{Description: "Cat"},
 										addr:   MustParseAddr("{Dog}/cat_friends/*"),
-										visitedRefs: map[string]bool{
-											specpathA + "#/definitions/Cat": true,
-											specpathA + "#/definitions/Dog": true,
+										visitedRefs: map[string]int{
+											specpathA + "#/definitions/Cat": 1,
+											specpathA + "#/definitions/Dog": 1,
 										},
 										ref: spec.MustCreateRef(specpathA + "#/definitions/Cat"),
 										Children: map[string]*Property{
 											"type": {
 												Schema: ptr(swg.Definitions["Pet"].Properties["type"]),
+												Meta:   PropertyMeta{Enum: []interface{}{"Dog", "Cat"}},
 												addr:   MustParseAddr("{Dog}/cat_friends/*/type"),
-												visitedRefs: map[string]bool{
-													specpathA + "#/definitions/Cat": true,
-													specpathA + "#/definitions/Dog": true,
-													specpathA + "#/definitions/Pet": true,
+												visitedRefs: map[string]int{
+													specpathA + "#/definitions/Cat": 1,
+													specpathA + "#/definitions/Dog": 1,
+													specpathA + "#/definitions/Pet": 1,
 												},
 												ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/type"),
 											},
 											"nickname": {
 												Schema: ptr(swg.Definitions["Pet"].Properties["nickname"]),
 												addr:   MustParseAddr("{Dog}/cat_friends/*/nickname"),
-												visitedRefs: map[string]bool{
-													specpathA + "#/definitions/Cat": true,
-													specpathA + "#/definitions/Dog": true,
-													specpathA + "#/definitions/Pet": true,
+												visitedRefs: map[string]int{
+													specpathA + "#/definitions/Cat": 1,
+													specpathA + "#/definitions/Dog": 1,
+													specpathA + "#/definitions/Pet": 1,
 												},
 												ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/nickname"),
 											},
 											"dog_friends": {
 												Schema: ptr(swg.Definitions["Cat"].Properties["dog_friends"]),
 												addr:   MustParseAddr("{Dog}/cat_friends/*/dog_friends"),
-												visitedRefs: map[string]bool{
-													specpathA + "#/definitions/Dog": true,
-													specpathA + "#/definitions/Cat": true,
+												visitedRefs: map[string]int{
+													specpathA + "#/definitions/Dog": 1,
+													specpathA + "#/definitions/Cat": 1,
 												},
 												ref: spec.MustCreateRef(specpathA + "#/definitions/Cat/properties/dog_friends"),
 											},
@@ -264,74 +269,78 @@ func TestExpand(t *testing.T) {
 						},
 						"Cat": {
 							Schema:             ptr(swg.Definitions["Cat"]),
+							Meta:               PropertyMeta{Description: "Cat"},
 							Discriminator:      "type",
 							DiscriminatorValue: "Cat",
 							addr:               MustParseAddr("{Cat}"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/Cat": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/Cat": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/Cat"),
 							Children: map[string]*Property{
 								"type": {
 									Schema: ptr(swg.Definitions["Pet"].Properties["type"]),
+									Meta:   PropertyMeta{Enum: []interface{}{"Dog", "Cat"}},
 									addr:   MustParseAddr("{Cat}/type"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/Cat": true,
-										specpathA + "#/definitions/Pet": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/Cat": 1,
+										specpathA + "#/definitions/Pet": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/type"),
 								},
 								"nickname": {
 									Schema: ptr(swg.Definitions["Pet"].Properties["nickname"]),
 									addr:   MustParseAddr("{Cat}/nickname"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/Cat": true,
-										specpathA + "#/definitions/Pet": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/Cat": 1,
+										specpathA + "#/definitions/Pet": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/nickname"),
 								},
 								"dog_friends": {
 									Schema: ptr(swg.Definitions["Cat"].Properties["dog_friends"]),
 									addr:   MustParseAddr("{Cat}/dog_friends"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/Cat": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/Cat": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/Cat/properties/dog_friends"),
 									Element: &Property{
 										Schema: ptr(swg.Definitions["Dog"]),
+										Meta:   PropertyMeta{Description: "Dog"},
 										addr:   MustParseAddr("{Cat}/dog_friends/*"),
-										visitedRefs: map[string]bool{
-											specpathA + "#/definitions/Cat": true,
-											specpathA + "#/definitions/Dog": true,
+										visitedRefs: map[string]int{
+											specpathA + "#/definitions/Cat": 1,
+											specpathA + "#/definitions/Dog": 1,
 										},
 										ref: spec.MustCreateRef(specpathA + "#/definitions/Dog"),
 										Children: map[string]*Property{
 											"type": {
 												Schema: ptr(swg.Definitions["Pet"].Properties["type"]),
+												Meta:   PropertyMeta{Enum: []interface{}{"Dog", "Cat"}},
 												addr:   MustParseAddr("{Cat}/dog_friends/*/type"),
-												visitedRefs: map[string]bool{
-													specpathA + "#/definitions/Cat": true,
-													specpathA + "#/definitions/Dog": true,
-													specpathA + "#/definitions/Pet": true,
+												visitedRefs: map[string]int{
+													specpathA + "#/definitions/Cat": 1,
+													specpathA + "#/definitions/Dog": 1,
+													specpathA + "#/definitions/Pet": 1,
 												},
 												ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/type"),
 											},
 											"nickname": {
 												Schema: ptr(swg.Definitions["Pet"].Properties["nickname"]),
 												addr:   MustParseAddr("{Cat}/dog_friends/*/nickname"),
-												visitedRefs: map[string]bool{
-													specpathA + "#/definitions/Cat": true,
-													specpathA + "#/definitions/Dog": true,
-													specpathA + "#/definitions/Pet": true,
+												visitedRefs: map[string]int{
+													specpathA + "#/definitions/Cat": 1,
+													specpathA + "#/definitions/Dog": 1,
+													specpathA + "#/definitions/Pet": 1,
 												},
 												ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/nickname"),
 											},
 											"cat_friends": {
 												Schema: ptr(swg.Definitions["Dog"].Properties["cat_friends"]),
 												addr:   MustParseAddr("{Cat}/dog_friends/*/cat_friends"),
-												visitedRefs: map[string]bool{
-													specpathA + "#/definitions/Cat": true,
-													specpathA + "#/definitions/Dog": true,
+												visitedRefs: map[string]int{
+													specpathA + "#/definitions/Cat": 1,
+													specpathA + "#/definitions/Dog": 1,
 												},
 												ref: spec.MustCreateRef(specpathA + "#/definitions/Dog/properties/cat_friends"),
 											},
@@ -352,72 +361,76 @@ func TestExpand(t *testing.T) {
 				swg := swgs[0]
 				expect := &Property{
 					Schema: ptr(swg.Definitions["Dog"]),
+					Meta:   PropertyMeta{Description: "Dog"},
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/Dog": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/Dog": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/Dog"),
 					Children: map[string]*Property{
 						"type": {
 							Schema: ptr(swg.Definitions["Pet"].Properties["type"]),
+							Meta:   PropertyMeta{Enum: []interface{}{"Dog", "Cat"}},
 							addr:   MustParseAddr("type"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/Dog": true,
-								specpathA + "#/definitions/Pet": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/Dog": 1,
+								specpathA + "#/definitions/Pet": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/type"),
 						},
 						"nickname": {
 							Schema: ptr(swg.Definitions["Pet"].Properties["nickname"]),
 							addr:   MustParseAddr("nickname"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/Dog": true,
-								specpathA + "#/definitions/Pet": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/Dog": 1,
+								specpathA + "#/definitions/Pet": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/nickname"),
 						},
 						"cat_friends": {
 							Schema: ptr(swg.Definitions["Dog"].Properties["cat_friends"]),
 							addr:   MustParseAddr("cat_friends"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/Dog": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/Dog": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/Dog/properties/cat_friends"),
 							Element: &Property{
 								Schema: ptr(swg.Definitions["Cat"]),
+								Meta:   PropertyMeta{Description: "Cat"},
 								addr:   MustParseAddr("cat_friends/*"),
-								visitedRefs: map[string]bool{
-									specpathA + "#/definitions/Cat": true,
-									specpathA + "#/definitions/Dog": true,
+								visitedRefs: map[string]int{
+									specpathA + "#/definitions/Cat": 1,
+									specpathA + "#/definitions/Dog": 1,
 								},
 								ref: spec.MustCreateRef(specpathA + "#/definitions/Cat"),
 								Children: map[string]*Property{
 									"type": {
 										Schema: ptr(swg.Definitions["Pet"].Properties["type"]),
+										Meta:   PropertyMeta{Enum: []interface{}{"Dog", "Cat"}},
 										addr:   MustParseAddr("cat_friends/*/type"),
-										visitedRefs: map[string]bool{
-											specpathA + "#/definitions/Cat": true,
-											specpathA + "#/definitions/Dog": true,
-											specpathA + "#/definitions/Pet": true,
+										visitedRefs: map[string]int{
+											specpathA + "#/definitions/Cat": 1,
+											specpathA + "#/definitions/Dog": 1,
+											specpathA + "#/definitions/Pet": 1,
 										},
 										ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/type"),
 									},
 									"nickname": {
 										Schema: ptr(swg.Definitions["Pet"].Properties["nickname"]),
 										addr:   MustParseAddr("cat_friends/*/nickname"),
-										visitedRefs: map[string]bool{
-											specpathA + "#/definitions/Cat": true,
-											specpathA + "#/definitions/Dog": true,
-											specpathA + "#/definitions/Pet": true,
+										visitedRefs: map[string]int{
+											specpathA + "#/definitions/Cat": 1,
+											specpathA + "#/definitions/Dog": 1,
+											specpathA + "#/definitions/Pet": 1,
 										},
 										ref: spec.MustCreateRef(specpathA + "#/definitions/Pet/properties/nickname"),
 									},
 									"dog_friends": {
 										Schema: ptr(swg.Definitions["Cat"].Properties["dog_friends"]),
 										addr:   MustParseAddr("cat_friends/*/dog_friends"),
-										visitedRefs: map[string]bool{
-											specpathA + "#/definitions/Dog": true,
-											specpathA + "#/definitions/Cat": true,
+										visitedRefs: map[string]int{
+											specpathA + "#/definitions/Dog": 1,
+											specpathA + "#/definitions/Cat": 1,
 										},
 										ref: spec.MustCreateRef(specpathA + "#/definitions/Cat/properties/dog_friends"),
 									},
@@ -437,8 +450,8 @@ func TestExpand(t *testing.T) {
 				expect := &Property{
 					Schema: ptr(swg.Definitions["MsPet"]),
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/MsPet": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/MsPet": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/MsPet"),
 					Variant: map[string]*Property{
@@ -447,17 +460,18 @@ func TestExpand(t *testing.T) {
 							Discriminator:      "type",
 							DiscriminatorValue: "CuteDog",
 							addr:               MustParseAddr("{CuteDog}"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/MsDog": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/MsDog": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/MsDog"),
 							Children: map[string]*Property{
 								"type": {
 									Schema: ptr(swg.Definitions["MsPet"].Properties["type"]),
+									Meta:   PropertyMeta{Enum: []interface{}{"CuteDog"}},
 									addr:   MustParseAddr("{CuteDog}/type"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/MsPet": true,
-										specpathA + "#/definitions/MsDog": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/MsPet": 1,
+										specpathA + "#/definitions/MsDog": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/MsPet/properties/type"),
 								},
@@ -476,8 +490,8 @@ func TestExpand(t *testing.T) {
 				expect := &Property{
 					Schema: ptr(swg.Definitions["ConflictBase"]),
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/ConflictBase": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/ConflictBase": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/ConflictBase"),
 					Variant: map[string]*Property{
@@ -486,17 +500,18 @@ func TestExpand(t *testing.T) {
 							Discriminator:      "type",
 							DiscriminatorValue: "ConflictVar",
 							addr:               MustParseAddr("{ConflictVar}"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/RealConflictVar": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/RealConflictVar": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/RealConflictVar"),
 							Children: map[string]*Property{
 								"type": {
 									Schema: ptr(swg.Definitions["ConflictBase"].Properties["type"]),
+									Meta:   PropertyMeta{Enum: []interface{}{"ConflictVar"}},
 									addr:   MustParseAddr("{ConflictVar}/type"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/RealConflictVar": true,
-										specpathA + "#/definitions/ConflictBase":    true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/RealConflictVar": 1,
+										specpathA + "#/definitions/ConflictBase":    1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/ConflictBase/properties/type"),
 								},
@@ -516,17 +531,17 @@ func TestExpand(t *testing.T) {
 				expect := &Property{
 					Schema: ptr(swgA.Definitions["UseExtBase"]),
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/UseExtBase": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/UseExtBase": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/UseExtBase"),
 					Children: map[string]*Property{
 						"foo": {
 							Schema: ptr(swgB.Definitions["BBase"]),
 							addr:   MustParseAddr("foo"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/UseExtBase": true,
-								specpathB + "#/definitions/BBase":      true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/UseExtBase": 1,
+								specpathB + "#/definitions/BBase":      1,
 							},
 							ref: spec.MustCreateRef(specpathB + "#/definitions/BBase"),
 							Variant: map[string]*Property{
@@ -535,19 +550,20 @@ func TestExpand(t *testing.T) {
 									Discriminator:      "type",
 									DiscriminatorValue: "BVar",
 									addr:               MustParseAddr("foo{BVar}"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/UseExtBase": true,
-										specpathB + "#/definitions/BarVar":     true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/UseExtBase": 1,
+										specpathB + "#/definitions/BarVar":     1,
 									},
 									ref: spec.MustCreateRef(specpathB + "#/definitions/BarVar"),
 									Children: map[string]*Property{
 										"type": {
 											Schema: ptr(swgB.Definitions["BBase"].Properties["type"]),
+											Meta:   PropertyMeta{Enum: []interface{}{"BVar"}},
 											addr:   MustParseAddr("foo{BVar}/type"),
-											visitedRefs: map[string]bool{
-												specpathA + "#/definitions/UseExtBase": true,
-												specpathB + "#/definitions/BBase":      true,
-												specpathB + "#/definitions/BarVar":     true,
+											visitedRefs: map[string]int{
+												specpathA + "#/definitions/UseExtBase": 1,
+												specpathB + "#/definitions/BBase":      1,
+												specpathB + "#/definitions/BarVar":     1,
 											},
 											ref: spec.MustCreateRef(specpathB + "#/definitions/BBase/properties/type"),
 										},
@@ -569,8 +585,8 @@ func TestExpand(t *testing.T) {
 				expect := &Property{
 					Schema: ptr(swg.Definitions["empty"]),
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/empty": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/empty": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/empty"),
 					Children: map[string]*Property{
@@ -581,16 +597,16 @@ func TestExpand(t *testing.T) {
 								},
 							},
 							addr: MustParseAddr("emptyObject"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/empty": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/empty": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/empty/properties/emptyObject"),
 						},
 						"emptyKey": {
 							Schema: ptr(swg.Definitions["empty"].Properties["emptyKey"]),
 							addr:   MustParseAddr("emptyKey"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/empty": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/empty": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/empty/properties/emptyKey"),
 							Element: &Property{
@@ -600,8 +616,8 @@ func TestExpand(t *testing.T) {
 									},
 								},
 								addr: MustParseAddr("emptyKey/*"),
-								visitedRefs: map[string]bool{
-									specpathA + "#/definitions/empty": true,
+								visitedRefs: map[string]int{
+									specpathA + "#/definitions/empty": 1,
 								},
 								ref: spec.MustCreateRef(specpathA + "#/definitions/empty/properties/emptyKey/additionalProperties"),
 							},
@@ -620,8 +636,8 @@ func TestExpand(t *testing.T) {
 				expect := &Property{
 					Schema: ptr(swg.Definitions["XBase"]),
 					addr:   RootAddr,
-					visitedRefs: map[string]bool{
-						specpathA + "#/definitions/XBase": true,
+					visitedRefs: map[string]int{
+						specpathA + "#/definitions/XBase": 1,
 					},
 					ref: spec.MustCreateRef(specpathA + "#/definitions/XBase"),
 					Variant: map[string]*Property{
@@ -630,17 +646,17 @@ func TestExpand(t *testing.T) {
 							DiscriminatorValue: "XVar1",
 							Schema:             ptr(swg.Definitions["XVar1"]),
 							addr:               MustParseAddr("{XVar1}"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/XVar1": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/XVar1": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/XVar1"),
 							Children: map[string]*Property{
 								"type": {
 									Schema: ptr(swg.Definitions["XBase"].Properties["type"]),
 									addr:   MustParseAddr("{XVar1}/type"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/XVar1": true,
-										specpathA + "#/definitions/XBase": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/XVar1": 1,
+										specpathA + "#/definitions/XBase": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/XBase/properties/type"),
 								},
@@ -651,18 +667,18 @@ func TestExpand(t *testing.T) {
 							DiscriminatorValue: "XVar2",
 							Schema:             ptr(swg.Definitions["XVar2"]),
 							addr:               MustParseAddr("{XVar2}"),
-							visitedRefs: map[string]bool{
-								specpathA + "#/definitions/XVar2": true,
+							visitedRefs: map[string]int{
+								specpathA + "#/definitions/XVar2": 1,
 							},
 							ref: spec.MustCreateRef(specpathA + "#/definitions/XVar2"),
 							Children: map[string]*Property{
 								"type": {
 									Schema: ptr(swg.Definitions["XBase"].Properties["type"]),
 									addr:   MustParseAddr("{XVar2}/type"),
-									visitedRefs: map[string]bool{
-										specpathA + "#/definitions/XVar2": true,
-										specpathA + "#/definitions/XVar1": true,
-										specpathA + "#/definitions/XBase": true,
+									visitedRefs: map[string]int{
+										specpathA + "#/definitions/XVar2": 1,
+										specpathA + "#/definitions/XVar1": 1,
+										specpathA + "#/definitions/XBase": 1,
 									},
 									ref: spec.MustCreateRef(specpathA + "#/definitions/XBase/properties/type"),
 								},
@@ -673,6 +689,66 @@ func TestExpand(t *testing.T) {
 				require.Equal(t, expect, root)
 			},
 		},
+		{
+			name: "null type",
+			ref:  specpathA + "#/definitions/nulltype",
+			verify: func(t *testing.T, root *Property, swgs ...*spec.Swagger) {
+				swg := swgs[0]
+				require.Equal(t, ptr(swg.Definitions["nulltype"].Properties["null"]), root.Children["null"].Schema)
+				require.Equal(t, spec.StringOrArray{"null"}, root.Children["nulls"].Schema.Type)
+				require.Nil(t, root.Children["nulls"].Variant)
+			},
+		},
+		{
+			name: "multi-type",
+			ref:  specpathA + "#/definitions/multitype",
+			verify: func(t *testing.T, root *Property, swgs ...*spec.Swagger) {
+				swg := swgs[0]
+				withType := func(schema spec.Schema, t string) *spec.Schema {
+					schema.Type = spec.StringOrArray{t}
+					return &schema
+				}
+				visited := map[string]int{
+					specpathA + "#/definitions/multitype": 1,
+				}
+				expect := &Property{
+					Schema:      ptr(swg.Definitions["multitype"]),
+					addr:        RootAddr,
+					visitedRefs: visited,
+					ref:         spec.MustCreateRef(specpathA + "#/definitions/multitype"),
+					Children: map[string]*Property{
+						"nullable": {
+							Schema:      withType(swg.Definitions["multitype"].Properties["nullable"], "string"),
+							addr:        MustParseAddr("nullable"),
+							visitedRefs: visited,
+							ref:         spec.MustCreateRef(specpathA + "#/definitions/multitype/properties/nullable"),
+						},
+						"multi": {
+							Schema:      ptr(swg.Definitions["multitype"].Properties["multi"]),
+							addr:        MustParseAddr("multi"),
+							visitedRefs: visited,
+							ref:         spec.MustCreateRef(specpathA + "#/definitions/multitype/properties/multi"),
+							VariantKind: PropertyVariantKindType,
+							Variant: map[string]*Property{
+								"string": {
+									Schema:      withType(swg.Definitions["multitype"].Properties["multi"], "string"),
+									addr:        MustParseAddr("multi{string}"),
+									visitedRefs: visited,
+									ref:         spec.MustCreateRef(specpathA + "#/definitions/multitype/properties/multi"),
+								},
+								"integer": {
+									Schema:      withType(swg.Definitions["multitype"].Properties["multi"], "integer"),
+									addr:        MustParseAddr("multi{integer}"),
+									visitedRefs: visited,
+									ref:         spec.MustCreateRef(specpathA + "#/definitions/multitype/properties/multi"),
+								},
+							},
+						},
+					},
+				}
+				require.Equal(t, expect, root)
+			},
+		},
 	}
 
 	for _, tt := range cases {
@@ -724,6 +800,195 @@ func TestExpandWithCache(t *testing.T) {
 	require.Equal(t, exp1.root, exp3.root)
 }
 
+func TestExpandWithPersistentCache(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(pwd, "testdata", "exp_a.json"))
+	require.NoError(t, err)
+	specpath := filepath.Join(t.TempDir(), "exp_a.json")
+	require.NoError(t, os.WriteFile(specpath, b, 0644))
+	cachedir := t.TempDir()
+
+	synthAll := func(root *Property) []interface{} {
+		var out []interface{}
+		instances, err := Monomorphization(root, nil)
+		require.NoError(t, err)
+		for _, v := range instances {
+			v := v
+			syn, err := NewSynthesizer(&v, ptr(NewRnd(nil)), nil)
+			require.NoError(t, err)
+			res, _ := syn.Synthesize()
+			out = append(out, res)
+		}
+		return out
+	}
+	expand := func(ref spec.Ref) (*Expander, bool) {
+		cache, err := NewPersistentExpanderCache(cachedir)
+		require.NoError(t, err)
+		exp, err := NewExpander(ref, &ExpanderOption{Cache: cache})
+		require.NoError(t, err)
+		hit := cache.load(exp)
+		require.NoError(t, exp.Expand())
+		return exp, hit
+	}
+
+	for _, model := range []string{"object", "Pet"} {
+		ref := spec.MustCreateRef(specpath + "#/definitions/" + model)
+
+		// First run populates the persisted cache
+		exp1, hit := expand(ref)
+		require.False(t, hit)
+
+		// Second run (with a new cache instance) loads from the persisted cache
+		exp2, hit := expand(ref)
+		require.True(t, hit)
+		require.Equal(t, synthAll(exp1.root), synthAll(exp2.root))
+		var addrs1, addrs2 []string
+		exp1.root.Walk(func(p *Property) bool { addrs1 = append(addrs1, p.addr.String()); return true })
+		exp2.root.Walk(func(p *Property) bool { addrs2 = append(addrs2, p.addr.String()); return true })
+		require.ElementsMatch(t, addrs1, addrs2)
+	}
+
+	// Changing the spec invalidates the persisted cache
+	require.NoError(t, os.WriteFile(specpath, append(b, '\n'), 0644))
+	_, hit := expand(spec.MustCreateRef(specpath + "#/definitions/object"))
+	require.False(t, hit)
+}
+
 func ptr[T any](input T) *T {
 	return &input
 }
+
+func TestExpandClientAddr(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	specpathA := filepath.Join(pwd, "testdata", "exp_a.json")
+
+	cachedir := t.TempDir()
+	newPersistentCache := func() *ExpanderCache {
+		cache, err := NewPersistentExpanderCache(cachedir)
+		require.NoError(t, err)
+		return cache
+	}
+
+	// The last persistent cache restores the one persisted by the previous one.
+	for _, cache := range []*ExpanderCache{nil, NewExpanderCache(), newPersistentCache(), newPersistentCache()} {
+		exp, err := NewExpander(spec.MustCreateRef(specpathA+"#/definitions/clientname"), &ExpanderOption{Cache: cache})
+		require.NoError(t, err)
+		require.NoError(t, exp.Expand())
+
+		addrs := map[string]string{}
+		exp.Root().Walk(func(p *Property) bool {
+			addrs[p.addr.String()] = p.ClientAddr().String()
+			return true
+		})
+		require.Equal(t, map[string]string{
+			"":                 "",
+			"properties":       "",
+			"properties/foo":   "Foo",
+			"properties/arr":   "arr",
+			"properties/arr/*": "arr/*",
+			"wire_name":        "clientName",
+			"plain":            "plain",
+		}, addrs)
+		require.Nil(t, exp.Root().Children["plain"].clientAddr)
+	}
+}
+
+func TestExpandMeta(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	specpathA := filepath.Join(pwd, "testdata", "exp_a.json")
+
+	exp, err := NewExpander(spec.MustCreateRef(specpathA+"#/definitions/meta"), nil)
+	require.NoError(t, err)
+	require.NoError(t, exp.Expand())
+
+	metas := map[string]PropertyMeta{}
+	exp.Root().Walk(func(p *Property) bool {
+		metas[p.addr.String()] = p.Meta
+		return true
+	})
+	require.Equal(t, map[string]PropertyMeta{
+		"": {},
+		"secret": {
+			Required:   true,
+			Secret:     true,
+			Mutability: []string{"create", "update"},
+		},
+		"ro": {
+			ReadOnly:    true,
+			Enum:        []interface{}{"a", "b"},
+			Description: "read only",
+		},
+		"ref": {
+			ReadOnly:    true,
+			Description: "overridden",
+		},
+		"ref/p": {},
+	}, metas)
+}
+
+func TestExpandCrossFileVariants(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	root := filepath.Join(pwd, "testdata", "xfile", "root.json")
+	base := filepath.Join(pwd, "testdata", "xfile", "base.json")
+
+	exp, err := NewExpander(spec.MustCreateRef(root+"#/definitions/Root"), nil)
+	require.NoError(t, err)
+	require.NoError(t, exp.Expand())
+	pet := exp.Root().Children["pet"]
+	require.Len(t, pet.Variant, 2)
+	require.Contains(t, pet.Variant["fish"].Children, "fin")
+	require.Contains(t, pet.Variant["fish"].Children, "kind")
+	require.Contains(t, pet.Variant["Dog"].Children, "bark")
+
+	// The variant spec is not reachable from the base spec
+	exp, err = NewExpander(spec.MustCreateRef(base+"#/definitions/Pet"), nil)
+	require.NoError(t, err)
+	require.NoError(t, exp.Expand())
+	require.Len(t, exp.Root().Variant, 1)
+	require.Contains(t, exp.Root().Variant, "Dog")
+}
+
+func TestPropertyLeafPositions(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	specpathA := filepath.Join(pwd, "testdata", "exp_a.json")
+
+	cases := []struct {
+		name   string
+		ref    string
+		expect []string
+	}{
+		{
+			name:   "object",
+			ref:    specpathA + "#/definitions/clientname",
+			expect: []string{"plain", "properties/arr/*", "properties/foo", "wire_name"},
+		},
+		{
+			name:   "empty object",
+			ref:    specpathA + "#/definitions/empty",
+			expect: nil,
+		},
+		{
+			name:   "polymorphic",
+			ref:    specpathA + "#/definitions/XBase",
+			expect: []string{"{XVar1}/type", "{XVar2}/type"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, err := NewExpander(spec.MustCreateRef(tt.ref), nil)
+			require.NoError(t, err)
+			require.NoError(t, exp.Expand())
+			var addrs []string
+			for _, pos := range exp.Root().LeafPositions() {
+				addrs = append(addrs, pos.Addr.String())
+			}
+			require.Equal(t, tt.expect, addrs)
+		})
+	}
+}
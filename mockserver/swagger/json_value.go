@@ -3,8 +3,11 @@ package swagger
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/go-openapi/jsonpointer"
 	"github.com/go-openapi/jsonreference"
 )
 
@@ -27,6 +30,10 @@ type JSONObject struct {
 func (obj JSONObject) JSONValue() interface{} {
 	m := map[string]interface{}{}
 	for k, v := range obj.value {
+		if v == nil {
+			m[k] = nil
+			continue
+		}
 		m[k] = v.JSONValue()
 	}
 	return m
@@ -44,6 +51,10 @@ type JSONArray struct {
 func (arr JSONArray) JSONValue() interface{} {
 	l := make([]interface{}, 0, len(arr.value))
 	for _, v := range arr.value {
+		if v == nil {
+			l = append(l, nil)
+			continue
+		}
 		l = append(l, v.JSONValue())
 	}
 	return l
@@ -82,12 +93,48 @@ func walkJSONValue(val JSONValue, fn func(val JSONValue)) {
 	return
 }
 
+const (
+	// ProvenanceValueMatch indicates the mapping is derived from the exactly matched values.
+	ProvenanceValueMatch = "value-match"
+	// ProvenanceTransform indicates the mapping is derived from the matched values after some transformation.
+	ProvenanceTransform = "transform"
+	// ProvenanceComposite indicates the mapping is derived from the API values that compose the application value.
+	ProvenanceComposite = "composite"
+	// ProvenanceVibration indicates the mapping is derived from the application properties changed by a vibration.
+	ProvenanceVibration = "vibration"
+)
+
+const (
+	// ConditionalAppear indicates the application property only appears when the API value changes.
+	ConditionalAppear = "appear"
+	// ConditionalDisappear indicates the application property disappears when the API value changes.
+	ConditionalDisappear = "disappear"
+)
+
 type JSONValuePos struct {
-	RootModel  RootModelInfo     `json:"root_model"`
-	Ref        jsonreference.Ref `json:"ref"`
-	Addr       PropertyAddr      `json:"addr"`
-	LinkLocal  string            `json:"link_local,omitempty"`
-	LinkGithub string            `json:"link_github,omitempty"`
+	RootModel RootModelInfo     `json:"root_model"`
+	Ref       jsonreference.Ref `json:"ref"`
+	Addr      PropertyAddr      `json:"addr"`
+	// ClientAddr is the client-visible address, which is omitted in JSON when it equals to the Addr.
+	ClientAddr PropertyAddr `json:"client_addr,omitempty"`
+	Meta       PropertyMeta `json:"meta"`
+	// Transform is the transformation that explains how the application value is derived from this API value, if not equal.
+	Transform string `json:"transform,omitempty"`
+	// Composite is the 1-based order of this API value among the ones that compose the application value, or 0 if the application value is not composite.
+	Composite int `json:"composite,omitempty"`
+	// Conditional is non-empty if the application property's presence depends on the API value (i.e. ConditionalAppear or ConditionalDisappear).
+	Conditional string `json:"conditional,omitempty"`
+	// Presence indicates the application property depends on the presence (or the array length) of the API property, rather than its value.
+	Presence bool `json:"presence,omitempty"`
+	// Provenance tells how the mapping is derived, which is one of the ProvenanceXXX.
+	Provenance string `json:"provenance,omitempty"`
+	// Confidence is the confidence score of the mapping, ranges in (0, 1].
+	Confidence float64 `json:"confidence,omitempty"`
+	// Conflicting indicates the application property pointer is kept concrete (e.g. "/0/p"), as the other application properties
+	// that share the same generalized pointer (e.g. "/*/p") are mapped to different API properties.
+	Conflicting bool   `json:"conflicting,omitempty"`
+	LinkLocal   string `json:"link_local,omitempty"`
+	LinkGithub  string `json:"link_github,omitempty"`
 }
 
 func (pos JSONValuePos) String() string {
@@ -99,9 +146,34 @@ func (pos JSONValuePos) MarshalJSON() ([]byte, error) {
 		"root_model":  pos.RootModel,
 		"ref":         pos.Ref.String(),
 		"addr":        pos.Addr.String(),
+		"meta":        pos.Meta,
 		"link_local":  pos.LinkLocal,
 		"link_github": pos.LinkGithub,
 	}
+	if clientAddr := pos.ClientAddr.String(); len(pos.ClientAddr) != 0 && clientAddr != pos.Addr.String() {
+		m["client_addr"] = clientAddr
+	}
+	if pos.Transform != "" {
+		m["transform"] = pos.Transform
+	}
+	if pos.Composite != 0 {
+		m["composite"] = pos.Composite
+	}
+	if pos.Conditional != "" {
+		m["conditional"] = pos.Conditional
+	}
+	if pos.Presence {
+		m["presence"] = true
+	}
+	if pos.Provenance != "" {
+		m["provenance"] = pos.Provenance
+	}
+	if pos.Confidence != 0 {
+		m["confidence"] = pos.Confidence
+	}
+	if pos.Conflicting {
+		m["conflicting"] = true
+	}
 	return json.Marshal(m)
 }
 
@@ -131,6 +203,47 @@ func (pos *JSONValuePos) UnmarshalJSON(b []byte) error {
 		}
 		pos.Addr = *addr
 	}
+	if v, ok := m["client_addr"]; ok {
+		addr, err := ParseAddr(v.(string))
+		if err != nil {
+			return err
+		}
+		pos.ClientAddr = addr.Copy()
+	} else if pos.Addr != nil {
+		pos.ClientAddr = pos.Addr.Copy()
+	}
+	if v, ok := m["meta"]; ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var meta PropertyMeta
+		if err := json.Unmarshal(b, &meta); err != nil {
+			return err
+		}
+		pos.Meta = meta
+	}
+	if v, ok := m["transform"]; ok {
+		pos.Transform = v.(string)
+	}
+	if v, ok := m["composite"]; ok {
+		pos.Composite = int(v.(float64))
+	}
+	if v, ok := m["conditional"]; ok {
+		pos.Conditional = v.(string)
+	}
+	if v, ok := m["presence"]; ok {
+		pos.Presence = v.(bool)
+	}
+	if v, ok := m["provenance"]; ok {
+		pos.Provenance = v.(string)
+	}
+	if v, ok := m["confidence"]; ok {
+		pos.Confidence = v.(float64)
+	}
+	if v, ok := m["conflicting"]; ok {
+		pos.Conflicting = v.(bool)
+	}
 	if v, ok := m["link_local"]; ok {
 		pos.LinkLocal = v.(string)
 	}
@@ -186,6 +299,45 @@ func JSONValueValueMap(l ...JSONValue) (map[string]*JSONValuePos, error) {
 	return out, nil
 }
 
+// JSONValueMapKeys returns the keys of all the map objects (i.e. objects defined by additionalProperties) among the input JSONValue(s).
+func JSONValueMapKeys(l ...JSONValue) map[string]bool {
+	out := map[string]bool{}
+	var walk func(val JSONValue)
+	walk = func(val JSONValue) {
+		switch val := val.(type) {
+		case JSONArray:
+			for _, v := range val.value {
+				walk(v)
+			}
+		case JSONObject:
+			for k, v := range val.value {
+				if pos := jsonValuePos(v); pos != nil && len(pos.Addr) != 0 && pos.Addr[len(pos.Addr)-1].Type == PropertyAddrStepTypeIndex {
+					out[k] = true
+				}
+				walk(v)
+			}
+		}
+	}
+	for _, v := range l {
+		walk(v)
+	}
+	return out
+}
+
+// jsonValuePos returns the position of any kind of JSONValue.
+func jsonValuePos(val JSONValue) *JSONValuePos {
+	switch val := val.(type) {
+	case JSONObject:
+		return val.pos
+	case JSONArray:
+		return val.pos
+	case nil:
+		return nil
+	default:
+		return val.JSONValuePos()
+	}
+}
+
 func FlattenJSONValueObjectByAddr(obj JSONObject) map[string]JSONValue {
 	out := map[string]JSONValue{}
 	fn := func(val JSONValue) {
@@ -202,6 +354,127 @@ func FlattenJSONValueObjectByAddr(obj JSONObject) map[string]JSONValue {
 	return out
 }
 
+// JSONValuePosByPointer returns the position of the JSON value (can be an object or an array) referenced by the JSON pointer.
+// The returned position is nil if the value is not defined by the API model.
+func JSONValuePosByPointer(val JSONValue, ptr string) (*JSONValuePos, error) {
+	p, err := jsonpointer.New(ptr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JSON pointer %q: %v", ptr, err)
+	}
+	for _, tk := range p.DecodedTokens() {
+		switch v := val.(type) {
+		case JSONObject:
+			nv, ok := v.value[tk]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found in %s", tk, ptr)
+			}
+			val = nv
+		case JSONArray:
+			idx, err := strconv.Atoi(tk)
+			if err != nil || idx < 0 || idx >= len(v.value) {
+				return nil, fmt.Errorf("invalid array index %q in %s", tk, ptr)
+			}
+			val = v.value[idx]
+		default:
+			return nil, fmt.Errorf("can't index %q in a non-container value in %s", tk, ptr)
+		}
+	}
+	return jsonValuePos(val), nil
+}
+
+// ResolveAddrPointer resolves the property address against the JSON value (that is unmarshalled with its property, e.g. a recorded response),
+// and returns the JSON pointer to the addressed value.
+// An index step selects the array element (or the map entry) by its key qualifier (e.g. "*[1]"), or otherwise the first one (in order of the
+// array index or the sorted map key) that is of the variant of the step (if any). The variants are checked against the properties of the JSON values.
+// The last step is allowed to address a non-existent value (e.g. a value to be added).
+func ResolveAddrPointer(val JSONValue, addr PropertyAddr) (string, error) {
+	var tks []string
+	for i, step := range addr {
+		last := i == len(addr)-1
+		switch step.Type {
+		case PropertyAddrStepTypeProp:
+			if step.Value == "" {
+				// The variant of the current value, e.g. the root model
+				break
+			}
+			obj, ok := val.(JSONObject)
+			if !ok {
+				return "", fmt.Errorf("%s: expect an object, got %T", addr[:i+1], val)
+			}
+			v, ok := obj.value[step.Value]
+			if !ok {
+				if last && step.Variant == "" {
+					return "/" + strings.Join(append(tks, jsonpointer.Escape(step.Value)), "/"), nil
+				}
+				return "", fmt.Errorf("%s: property not found", addr[:i+1])
+			}
+			tks = append(tks, jsonpointer.Escape(step.Value))
+			val = v
+		case PropertyAddrStepTypeIndex:
+			var (
+				keys   []string
+				values = map[string]JSONValue{}
+			)
+			switch v := val.(type) {
+			case JSONArray:
+				for idx, elem := range v.value {
+					keys = append(keys, strconv.Itoa(idx))
+					values[strconv.Itoa(idx)] = elem
+				}
+			case JSONObject:
+				keys = make([]string, 0, len(v.value))
+				for k, elem := range v.value {
+					keys = append(keys, k)
+					values[k] = elem
+				}
+				sort.Strings(keys)
+			default:
+				return "", fmt.Errorf("%s: expect an array or a map, got %T", addr[:i+1], val)
+			}
+			if step.Key != "" {
+				v, ok := values[step.Key]
+				if !ok {
+					if _, isArray := val.(JSONArray); last && step.Variant == "" && (!isArray || step.Key == strconv.Itoa(len(keys))) {
+						return "/" + strings.Join(append(tks, jsonpointer.Escape(step.Key)), "/"), nil
+					}
+					return "", fmt.Errorf("%s: key not found", addr[:i+1])
+				}
+				tks = append(tks, jsonpointer.Escape(step.Key))
+				val = v
+				break
+			}
+			var found bool
+			for _, k := range keys {
+				if step.Variant == "" || jsonValueVariant(values[k]) == step.Variant {
+					tks = append(tks, jsonpointer.Escape(k))
+					val = values[k]
+					found = true
+					break
+				}
+			}
+			if !found {
+				return "", fmt.Errorf("%s: no element found", addr[:i+1])
+			}
+			continue
+		}
+		if step.Variant != "" {
+			if variant := jsonValueVariant(val); variant != step.Variant {
+				return "", fmt.Errorf("%s: variant mismatch, got %q", addr[:i+1], variant)
+			}
+		}
+	}
+	return "/" + strings.Join(tks, "/"), nil
+}
+
+// jsonValueVariant returns the variant of the JSON value, according to its property address.
+func jsonValueVariant(val JSONValue) string {
+	pos := jsonValuePos(val)
+	if pos == nil || len(pos.Addr) == 0 {
+		return ""
+	}
+	return pos.Addr[len(pos.Addr)-1].Variant
+}
+
 func UnmarshalJSONToJSONValue(b []byte, root *Property) (JSONValue, error) {
 	var val interface{}
 
@@ -211,30 +484,53 @@ func UnmarshalJSONToJSONValue(b []byte, root *Property) (JSONValue, error) {
 
 	var jsonVal func(v interface{}, prop *Property) (JSONValue, error)
 	jsonVal = func(v interface{}, prop *Property) (JSONValue, error) {
+		if v == nil {
+			return nil, nil
+		}
+
 		// In case the property is polymorphic, get the variant based on the input json value
-		if prop != nil && len(prop.Variant) != 0 {
-			v := v.(map[string]interface{})
-			var randomVariant *Property
-			for _, v := range prop.Variant {
-				randomVariant = v
-				break
+		if prop != nil && len(prop.Variant) == 1 && prop.VariantKind != PropertyVariantKindDiscriminator {
+			// The variant is already chosen (e.g. the property is from a monomorphized instance). It is not guessed from the value,
+			// as the alternatives might be indistinguishable by the value.
+			for _, variant := range prop.Variant {
+				prop = variant
 			}
-			discriminator := randomVariant.Discriminator
-			dvalue, ok := v[discriminator].(string)
-			if !ok {
-				return nil, fmt.Errorf("value of the discriminator %q is not a string in JSON %v, got=%T", discriminator, v, v[discriminator])
+		} else if prop != nil && len(prop.Variant) != 0 {
+			switch prop.VariantKind {
+			case PropertyVariantKindType:
+				t, err := jsonValueSchemaType(v, prop.Variant)
+				if err != nil {
+					return nil, err
+				}
+				prop = prop.Variant[t]
+			case PropertyVariantKindOneOf, PropertyVariantKindAnyOf:
+				prop = matchCompositionVariant(v, prop.Variant)
+			default:
+				v, ok := v.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("polymorphic value is not an object, got=%T", v)
+				}
+				var randomVariant *Property
+				for _, v := range prop.Variant {
+					randomVariant = v
+					break
+				}
+				discriminator := randomVariant.Discriminator
+				if _, ok := v[discriminator]; !ok {
+					return nil, fmt.Errorf("%s: polymorphic object misses the discriminator %q, expect one of %v", prop.addr, discriminator, sortedKeys(prop.Variant))
+				}
+				dvalue, ok := v[discriminator].(string)
+				if !ok {
+					return nil, fmt.Errorf("value of the discriminator %q is not a string in JSON %v, got=%T", discriminator, v, v[discriminator])
+				}
+				prop = prop.Variant[dvalue]
 			}
-			prop = prop.Variant[dvalue]
 		}
 
 		var pos *JSONValuePos
 
 		if prop != nil {
-			pos = &JSONValuePos{
-				Addr:      prop.addr,
-				Ref:       prop.ref.Ref,
-				RootModel: prop.RootModel,
-			}
+			pos = prop.jsonValuePos()
 		}
 		switch v := v.(type) {
 		case float64:
@@ -252,8 +548,6 @@ func UnmarshalJSONToJSONValue(b []byte, root *Property) (JSONValue, error) {
 				value: v,
 				pos:   pos,
 			}, nil
-		case nil:
-			return nil, nil
 		case []interface{}:
 			var p *Property
 			if prop != nil {
@@ -300,3 +594,81 @@ func UnmarshalJSONToJSONValue(b []byte, root *Property) (JSONValue, error) {
 
 	return jsonVal(val, root)
 }
+
+// jsonValueSchemaType returns the schema type of the JSON value, which is one of the keys of the type variants.
+// A JSON number matches the "integer" variant if it is a whole number, and falls back to the "integer" or "number" variant, whichever exists.
+func jsonValueSchemaType(v interface{}, variants map[string]*Property) (string, error) {
+	candidates := []string{jsonValueKind(v)}
+	if f, ok := v.(float64); ok {
+		candidates = []string{"number", "integer"}
+		if f == float64(int64(f)) {
+			candidates = []string{"integer", "number"}
+		}
+	}
+	for _, t := range candidates {
+		if _, ok := variants[t]; ok {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("no type variant matches the JSON value %v (type: %T)", v, v)
+}
+
+// jsonValueKind returns the schema type that corresponds to the kind of the JSON value.
+func jsonValueKind(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return ""
+}
+
+// matchCompositionVariant returns the oneOf/anyOf variant that best matches the JSON value.
+// For object values, the variant that has the most properties defined in the value wins, while the undefined ones are penalized.
+// Ties are broken by the variant label order.
+func matchCompositionVariant(v interface{}, variants map[string]*Property) *Property {
+	keys := make([]string, 0, len(variants))
+	for k := range variants {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		best      *Property
+		bestScore int
+	)
+	for _, k := range keys {
+		variant := variants[k]
+		if variant.Schema != nil && len(variant.Schema.Type) == 1 {
+			t := variant.Schema.Type[0]
+			vt := jsonValueKind(v)
+			if f, ok := v.(float64); ok && t == "integer" && f == float64(int64(f)) {
+				vt = "integer"
+			}
+			if vt != t {
+				continue
+			}
+		}
+		var score int
+		if obj, ok := v.(map[string]interface{}); ok && variant.Children != nil {
+			for k := range obj {
+				if _, defined := variant.Children[k]; defined {
+					score++
+				} else {
+					score--
+				}
+			}
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = variant, score
+		}
+	}
+	return best
+}
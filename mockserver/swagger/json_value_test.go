@@ -26,22 +26,25 @@ func TestJSONValueValueMap(t *testing.T) {
 						"p1": JSONPrimitive[float64]{
 							value: 0.5,
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("p1"),
-								Addr: MustParseAddr("p1"),
+								Ref:        jsonreference.MustCreateRef("p1"),
+								Addr:       MustParseAddr("p1"),
+								ClientAddr: MustParseAddr("p1"),
 							},
 						},
 						"p2": JSONPrimitive[string]{
 							value: "abc",
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("p2"),
-								Addr: MustParseAddr("p2"),
+								Ref:        jsonreference.MustCreateRef("p2"),
+								Addr:       MustParseAddr("p2"),
+								ClientAddr: MustParseAddr("p2"),
 							},
 						},
 						"p3": JSONPrimitive[bool]{
 							value: true,
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("p3"),
-								Addr: MustParseAddr("p3"),
+								Ref:        jsonreference.MustCreateRef("p3"),
+								Addr:       MustParseAddr("p3"),
+								ClientAddr: MustParseAddr("p3"),
 							},
 						},
 					},
@@ -49,16 +52,19 @@ func TestJSONValueValueMap(t *testing.T) {
 			},
 			expect: map[string]*JSONValuePos{
 				"0.5": {
-					Ref:  jsonreference.MustCreateRef("p1"),
-					Addr: MustParseAddr("p1"),
+					Ref:        jsonreference.MustCreateRef("p1"),
+					Addr:       MustParseAddr("p1"),
+					ClientAddr: MustParseAddr("p1"),
 				},
 				"abc": {
-					Ref:  jsonreference.MustCreateRef("p2"),
-					Addr: MustParseAddr("p2"),
+					Ref:        jsonreference.MustCreateRef("p2"),
+					Addr:       MustParseAddr("p2"),
+					ClientAddr: MustParseAddr("p2"),
 				},
 				"TRUE": {
-					Ref:  jsonreference.MustCreateRef("p3"),
-					Addr: MustParseAddr("p3"),
+					Ref:        jsonreference.MustCreateRef("p3"),
+					Addr:       MustParseAddr("p3"),
+					ClientAddr: MustParseAddr("p3"),
 				},
 			},
 		},
@@ -70,8 +76,9 @@ func TestJSONValueValueMap(t *testing.T) {
 						JSONPrimitive[bool]{
 							value: true,
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("*"),
-								Addr: MustParseAddr("*"),
+								Ref:        jsonreference.MustCreateRef("*"),
+								Addr:       MustParseAddr("*"),
+								ClientAddr: MustParseAddr("*"),
 							},
 						},
 					},
@@ -79,8 +86,9 @@ func TestJSONValueValueMap(t *testing.T) {
 			},
 			expect: map[string]*JSONValuePos{
 				"TRUE": {
-					Ref:  jsonreference.MustCreateRef("*"),
-					Addr: MustParseAddr("*"),
+					Ref:        jsonreference.MustCreateRef("*"),
+					Addr:       MustParseAddr("*"),
+					ClientAddr: MustParseAddr("*"),
 				},
 			},
 		},
@@ -92,22 +100,25 @@ func TestJSONValueValueMap(t *testing.T) {
 						"p1": JSONPrimitive[float64]{
 							value: 0.5,
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("p1"),
-								Addr: MustParseAddr("p1"),
+								Ref:        jsonreference.MustCreateRef("p1"),
+								Addr:       MustParseAddr("p1"),
+								ClientAddr: MustParseAddr("p1"),
 							},
 						},
 						"p2": JSONPrimitive[string]{
 							value: "abc",
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("p2"),
-								Addr: MustParseAddr("p2"),
+								Ref:        jsonreference.MustCreateRef("p2"),
+								Addr:       MustParseAddr("p2"),
+								ClientAddr: MustParseAddr("p2"),
 							},
 						},
 						"p3": JSONPrimitive[bool]{
 							value: true,
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("p3"),
-								Addr: MustParseAddr("p3"),
+								Ref:        jsonreference.MustCreateRef("p3"),
+								Addr:       MustParseAddr("p3"),
+								ClientAddr: MustParseAddr("p3"),
 							},
 						},
 					},
@@ -117,8 +128,9 @@ func TestJSONValueValueMap(t *testing.T) {
 						JSONPrimitive[bool]{
 							value: true,
 							pos: &JSONValuePos{
-								Ref:  jsonreference.MustCreateRef("*"),
-								Addr: MustParseAddr("*"),
+								Ref:        jsonreference.MustCreateRef("*"),
+								Addr:       MustParseAddr("*"),
+								ClientAddr: MustParseAddr("*"),
 							},
 						},
 					},
@@ -126,12 +138,14 @@ func TestJSONValueValueMap(t *testing.T) {
 			},
 			expect: map[string]*JSONValuePos{
 				"0.5": {
-					Ref:  jsonreference.MustCreateRef("p1"),
-					Addr: MustParseAddr("p1"),
+					Ref:        jsonreference.MustCreateRef("p1"),
+					Addr:       MustParseAddr("p1"),
+					ClientAddr: MustParseAddr("p1"),
 				},
 				"abc": {
-					Ref:  jsonreference.MustCreateRef("p2"),
-					Addr: MustParseAddr("p2"),
+					Ref:        jsonreference.MustCreateRef("p2"),
+					Addr:       MustParseAddr("p2"),
+					ClientAddr: MustParseAddr("p2"),
 				},
 			},
 		},
@@ -187,21 +201,24 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 							JSONPrimitive[string]{
 								value: "b",
 								pos: &JSONValuePos{
-									Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/array/items"),
-									Addr: MustParseAddr("array/*"),
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/array/items"),
+									Addr:       MustParseAddr("array/*"),
+									ClientAddr: MustParseAddr("array/*"),
 								},
 							},
 						},
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/array"),
-							Addr: MustParseAddr("array"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/array"),
+							Addr:       MustParseAddr("array"),
+							ClientAddr: MustParseAddr("array"),
 						},
 					},
 					"boolean": JSONPrimitive[bool]{
 						value: true,
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/boolean"),
-							Addr: MustParseAddr("boolean"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/boolean"),
+							Addr:       MustParseAddr("boolean"),
+							ClientAddr: MustParseAddr("boolean"),
 						},
 					},
 					"emptyObject": JSONObject{
@@ -212,15 +229,17 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 							},
 						},
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/emptyObject"),
-							Addr: MustParseAddr("emptyObject"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/emptyObject"),
+							Addr:       MustParseAddr("emptyObject"),
+							ClientAddr: MustParseAddr("emptyObject"),
 						},
 					},
 					"integer": JSONPrimitive[float64]{
 						value: 1,
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/integer"),
-							Addr: MustParseAddr("integer"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/integer"),
+							Addr:       MustParseAddr("integer"),
+							ClientAddr: MustParseAddr("integer"),
 						},
 					},
 					"map": JSONObject{
@@ -228,21 +247,24 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 							"KEY": JSONPrimitive[string]{
 								value: "c",
 								pos: &JSONValuePos{
-									Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/map/additionalProperties"),
-									Addr: MustParseAddr("map/*"),
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/map/additionalProperties"),
+									Addr:       MustParseAddr("map/*"),
+									ClientAddr: MustParseAddr("map/*"),
 								},
 							},
 						},
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/map"),
-							Addr: MustParseAddr("map"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/map"),
+							Addr:       MustParseAddr("map"),
+							ClientAddr: MustParseAddr("map"),
 						},
 					},
 					"number": JSONPrimitive[float64]{
 						value: 1.5,
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/number"),
-							Addr: MustParseAddr("number"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/number"),
+							Addr:       MustParseAddr("number"),
+							ClientAddr: MustParseAddr("number"),
 						},
 					},
 					"object": JSONObject{
@@ -250,8 +272,9 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 							"p1": JSONPrimitive[string]{
 								value: "d",
 								pos: &JSONValuePos{
-									Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object/properties/p1"),
-									Addr: MustParseAddr("object/p1"),
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object/properties/p1"),
+									Addr:       MustParseAddr("object/p1"),
+									ClientAddr: MustParseAddr("object/p1"),
 								},
 							},
 							"obj": JSONObject{
@@ -259,33 +282,38 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 									"pp1": JSONPrimitive[float64]{
 										value: 2,
 										pos: &JSONValuePos{
-											Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object/properties/obj/properties/pp1"),
-											Addr: MustParseAddr("object/obj/pp1"),
+											Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object/properties/obj/properties/pp1"),
+											Addr:       MustParseAddr("object/obj/pp1"),
+											ClientAddr: MustParseAddr("object/obj/pp1"),
 										},
 									},
 								},
 								pos: &JSONValuePos{
-									Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object/properties/obj"),
-									Addr: MustParseAddr("object/obj"),
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object/properties/obj"),
+									Addr:       MustParseAddr("object/obj"),
+									ClientAddr: MustParseAddr("object/obj"),
 								},
 							},
 						},
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object"),
-							Addr: MustParseAddr("object"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/object"),
+							Addr:       MustParseAddr("object"),
+							ClientAddr: MustParseAddr("object"),
 						},
 					},
 					"string": JSONPrimitive[string]{
 						value: "e",
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/string"),
-							Addr: MustParseAddr("string"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object/properties/string"),
+							Addr:       MustParseAddr("string"),
+							ClientAddr: MustParseAddr("string"),
 						},
 					},
 				},
 				pos: &JSONValuePos{
-					Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/object"),
-					Addr: MustParseAddr(""),
+					Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/object"),
+					Addr:       MustParseAddr(""),
+					ClientAddr: MustParseAddr(""),
 				},
 			},
 		},
@@ -301,21 +329,25 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 					"type": JSONPrimitive[string]{
 						value: "var1",
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/base/properties/type"),
-							Addr: MustParseAddr("{var1}/type"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/base/properties/type"),
+							Addr:       MustParseAddr("{var1}/type"),
+							ClientAddr: MustParseAddr("{var1}/type"),
+							Meta:       PropertyMeta{Enum: []interface{}{"var1", "var2"}},
 						},
 					},
 					"prop1": JSONPrimitive[string]{
 						value: "foo",
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1/properties/prop1"),
-							Addr: MustParseAddr("{var1}/prop1"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1/properties/prop1"),
+							Addr:       MustParseAddr("{var1}/prop1"),
+							ClientAddr: MustParseAddr("{var1}/prop1"),
 						},
 					},
 				},
 				pos: &JSONValuePos{
-					Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1"),
-					Addr: MustParseAddr("{var1}"),
+					Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1"),
+					Addr:       MustParseAddr("{var1}"),
+					ClientAddr: MustParseAddr("{var1}"),
 				},
 			},
 		},
@@ -335,27 +367,208 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 							"type": JSONPrimitive[string]{
 								value: "var1",
 								pos: &JSONValuePos{
-									Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/base/properties/type"),
-									Addr: MustParseAddr("prop{var1}/type"),
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/base/properties/type"),
+									Addr:       MustParseAddr("prop{var1}/type"),
+									ClientAddr: MustParseAddr("prop{var1}/type"),
+									Meta:       PropertyMeta{Enum: []interface{}{"var1", "var2"}},
 								},
 							},
 							"prop1": JSONPrimitive[string]{
 								value: "foo",
 								pos: &JSONValuePos{
-									Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1/properties/prop1"),
-									Addr: MustParseAddr("prop{var1}/prop1"),
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1/properties/prop1"),
+									Addr:       MustParseAddr("prop{var1}/prop1"),
+									ClientAddr: MustParseAddr("prop{var1}/prop1"),
 								},
 							},
 						},
 						pos: &JSONValuePos{
-							Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1"),
-							Addr: MustParseAddr("prop{var1}"),
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1"),
+							Addr:       MustParseAddr("prop{var1}"),
+							ClientAddr: MustParseAddr("prop{var1}"),
 						},
 					},
 				},
 				pos: &JSONValuePos{
-					Ref:  jsonreference.MustCreateRef(specpathSyn + "#/definitions/use_base"),
-					Addr: MustParseAddr(""),
+					Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/use_base"),
+					Addr:       MustParseAddr(""),
+					ClientAddr: MustParseAddr(""),
+				},
+			},
+		},
+		{
+			ref: specpathSyn + "#/definitions/array_of_base",
+			input: `
+[
+  {
+    "type": "var1",
+    "prop1": "foo"
+  },
+  {
+    "type": "var2",
+    "prop2": "bar"
+  }
+]`,
+			expect: JSONArray{
+				value: []JSONValue{
+					JSONObject{
+						value: map[string]JSONValue{
+							"type": JSONPrimitive[string]{
+								value: "var1",
+								pos: &JSONValuePos{
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/base/properties/type"),
+									Addr:       MustParseAddr("*{var1}/type"),
+									ClientAddr: MustParseAddr("*{var1}/type"),
+									Meta:       PropertyMeta{Enum: []interface{}{"var1", "var2"}},
+								},
+							},
+							"prop1": JSONPrimitive[string]{
+								value: "foo",
+								pos: &JSONValuePos{
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1/properties/prop1"),
+									Addr:       MustParseAddr("*{var1}/prop1"),
+									ClientAddr: MustParseAddr("*{var1}/prop1"),
+								},
+							},
+						},
+						pos: &JSONValuePos{
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var1"),
+							Addr:       MustParseAddr("*{var1}"),
+							ClientAddr: MustParseAddr("*{var1}"),
+						},
+					},
+					JSONObject{
+						value: map[string]JSONValue{
+							"type": JSONPrimitive[string]{
+								value: "var2",
+								pos: &JSONValuePos{
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/base/properties/type"),
+									Addr:       MustParseAddr("*{var2}/type"),
+									ClientAddr: MustParseAddr("*{var2}/type"),
+									Meta:       PropertyMeta{Enum: []interface{}{"var1", "var2"}},
+								},
+							},
+							"prop2": JSONPrimitive[string]{
+								value: "bar",
+								pos: &JSONValuePos{
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var2/properties/prop2"),
+									Addr:       MustParseAddr("*{var2}/prop2"),
+									ClientAddr: MustParseAddr("*{var2}/prop2"),
+								},
+							},
+						},
+						pos: &JSONValuePos{
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/var2"),
+							Addr:       MustParseAddr("*{var2}"),
+							ClientAddr: MustParseAddr("*{var2}"),
+						},
+					},
+				},
+				pos: &JSONValuePos{
+					Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/array_of_base"),
+					Addr:       MustParseAddr(""),
+					ClientAddr: MustParseAddr(""),
+				},
+			},
+		},
+		{
+			ref: specpathSyn + "#/definitions/multitype",
+			input: `
+{
+  "nullable": null,
+  "multi": 1,
+  "obj": ["foo"]
+}`,
+			expect: JSONObject{
+				value: map[string]JSONValue{
+					"nullable": nil,
+					"multi": JSONPrimitive[float64]{
+						value: 1,
+						pos: &JSONValuePos{
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/multitype/properties/multi"),
+							Addr:       MustParseAddr("multi{integer}"),
+							ClientAddr: MustParseAddr("multi{integer}"),
+						},
+					},
+					"obj": JSONArray{
+						value: []JSONValue{
+							JSONPrimitive[string]{
+								value: "foo",
+								pos: &JSONValuePos{
+									Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/multitype/properties/obj/items"),
+									Addr:       MustParseAddr("obj{array}/*"),
+									ClientAddr: MustParseAddr("obj{array}/*"),
+								},
+							},
+						},
+						pos: &JSONValuePos{
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/multitype/properties/obj"),
+							Addr:       MustParseAddr("obj{array}"),
+							ClientAddr: MustParseAddr("obj{array}"),
+						},
+					},
+				},
+				pos: &JSONValuePos{
+					Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/multitype"),
+					Addr:       MustParseAddr(""),
+					ClientAddr: MustParseAddr(""),
+				},
+			},
+		},
+		{
+			ref: specpathSyn + "#/definitions/oneof",
+			input: `
+{
+  "common": "foo",
+  "meow": "bar"
+}`,
+			expect: JSONObject{
+				value: map[string]JSONValue{
+					"common": JSONPrimitive[string]{
+						value: "foo",
+						pos: &JSONValuePos{
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/oneof/properties/common"),
+							Addr:       MustParseAddr("{cat}/common"),
+							ClientAddr: MustParseAddr("{cat}/common"),
+						},
+					},
+					"meow": JSONPrimitive[string]{
+						value: "bar",
+						pos: &JSONValuePos{
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/oneof/oneOf/0/properties/meow"),
+							Addr:       MustParseAddr("{cat}/meow"),
+							ClientAddr: MustParseAddr("{cat}/meow"),
+						},
+					},
+				},
+				pos: &JSONValuePos{
+					Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/oneof/oneOf/0"),
+					Addr:       MustParseAddr("{cat}"),
+					ClientAddr: MustParseAddr("{cat}"),
+				},
+			},
+		},
+		{
+			ref: specpathSyn + "#/definitions/anyof",
+			input: `
+{
+  "v": 1
+}`,
+			expect: JSONObject{
+				value: map[string]JSONValue{
+					"v": JSONPrimitive[float64]{
+						value: 1,
+						pos: &JSONValuePos{
+							Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/anyof/properties/v/anyOf/1"),
+							Addr:       MustParseAddr("v{1}"),
+							ClientAddr: MustParseAddr("v{1}"),
+						},
+					},
+				},
+				pos: &JSONValuePos{
+					Ref:        jsonreference.MustCreateRef(specpathSyn + "#/definitions/anyof"),
+					Addr:       MustParseAddr(""),
+					ClientAddr: MustParseAddr(""),
 				},
 			},
 		},
@@ -374,6 +587,74 @@ func TestUnmarshalJSONToJSONValue(t *testing.T) {
 	}
 }
 
+func TestJSONValueMapKeys(t *testing.T) {
+	input := JSONObject{
+		value: map[string]JSONValue{
+			"map": JSONObject{
+				value: map[string]JSONValue{
+					"KEY": JSONPrimitive[string]{
+						value: "a",
+						pos: &JSONValuePos{
+							Addr:       MustParseAddr("map/*"),
+							ClientAddr: MustParseAddr("map/*"),
+						},
+					},
+					"KEY1": JSONObject{
+						value: map[string]JSONValue{
+							"p1": JSONPrimitive[string]{
+								value: "b",
+								pos: &JSONValuePos{
+									Addr:       MustParseAddr("map/*/p1"),
+									ClientAddr: MustParseAddr("map/*/p1"),
+								},
+							},
+						},
+						pos: &JSONValuePos{
+							Addr:       MustParseAddr("map/*"),
+							ClientAddr: MustParseAddr("map/*"),
+						},
+					},
+				},
+				pos: &JSONValuePos{
+					Addr:       MustParseAddr("map"),
+					ClientAddr: MustParseAddr("map"),
+				},
+			},
+			"array": JSONArray{
+				value: []JSONValue{
+					JSONObject{
+						value: map[string]JSONValue{
+							"p2": JSONPrimitive[string]{
+								value: "c",
+								pos: &JSONValuePos{
+									Addr:       MustParseAddr("array/*/p2"),
+									ClientAddr: MustParseAddr("array/*/p2"),
+								},
+							},
+						},
+						pos: &JSONValuePos{
+							Addr:       MustParseAddr("array/*"),
+							ClientAddr: MustParseAddr("array/*"),
+						},
+					},
+				},
+				pos: &JSONValuePos{
+					Addr:       MustParseAddr("array"),
+					ClientAddr: MustParseAddr("array"),
+				},
+			},
+			"undefined": JSONPrimitive[string]{
+				value: "d",
+			},
+		},
+		pos: &JSONValuePos{
+			Addr:       MustParseAddr(""),
+			ClientAddr: MustParseAddr(""),
+		},
+	}
+	require.Equal(t, map[string]bool{"KEY": true, "KEY1": true}, JSONValueMapKeys(input))
+}
+
 func TestUnmarshalJSONValuePos(t *testing.T) {
 	var pos JSONValuePos
 	input := []byte(`{
@@ -384,6 +665,22 @@ func TestUnmarshalJSONValuePos(t *testing.T) {
   },
   "ref": "p1#/foo/bar",
   "addr": "a.b",
+  "client_addr": "a/c",
+  "meta": {
+    "required": true,
+    "read_only": true,
+    "secret": true,
+    "mutability": ["read"],
+    "enum": ["a", "b"],
+    "description": "foo"
+  },
+  "transform": "lower",
+  "composite": 1,
+  "conditional": "appear",
+  "presence": true,
+  "provenance": "transform",
+  "confidence": 0.5,
+  "conflicting": true,
   "link_local": "p1/p2:1:2",
   "link_github": "https://github.com/blah"
 }`)
@@ -393,3 +690,123 @@ func TestUnmarshalJSONValuePos(t *testing.T) {
 	require.NoError(t, err)
 	require.JSONEq(t, string(input), string(b))
 }
+
+func TestMarshalJSONValuePosClientAddr(t *testing.T) {
+	pos := JSONValuePos{
+		Ref:        jsonreference.MustCreateRef("p1#/foo/bar"),
+		Addr:       MustParseAddr("a/b"),
+		ClientAddr: MustParseAddr("a/b"),
+	}
+	b, err := json.Marshal(pos)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "client_addr")
+
+	var npos JSONValuePos
+	require.NoError(t, json.Unmarshal(b, &npos))
+	require.Equal(t, pos.ClientAddr, npos.ClientAddr)
+}
+
+func TestResolveAddrPointer(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	exp, err := NewExpander(spec.MustCreateRef(filepath.Join(pwd, "testdata", "exp_a.json")+"#/definitions/Pet"), &ExpanderOption{RecursionDepth: 2})
+	require.NoError(t, err)
+	require.NoError(t, exp.Expand())
+
+	val, err := UnmarshalJSONToJSONValue([]byte(`{
+  "type": "Dog",
+  "nickname": "b",
+  "cat_friends": [
+    {"type": "Cat", "nickname": "c"},
+    {"type": "Cat", "nickname": "d"}
+  ]
+}`), exp.Root())
+	require.NoError(t, err)
+
+	cases := []struct {
+		addr   string
+		expect string
+		iserr  bool
+	}{
+		{
+			addr:   "{Dog}/nickname",
+			expect: "/nickname",
+		},
+		{
+			addr:   "{Dog}/cat_friends/*/nickname",
+			expect: "/cat_friends/0/nickname",
+		},
+		{
+			addr:   "{Dog}/cat_friends/*[1]/nickname",
+			expect: "/cat_friends/1/nickname",
+		},
+		{
+			addr:   "{Dog}/cat_friends/*[2]",
+			expect: "/cat_friends/2",
+		},
+		{
+			addr:   "{Dog}/new",
+			expect: "/new",
+		},
+		{
+			addr:  "{Cat}/nickname",
+			iserr: true,
+		},
+		{
+			addr:  "{Dog}/cat_friends/*[3]",
+			iserr: true,
+		},
+		{
+			addr:  "{Dog}/cat_friends/*{Dog}/nickname",
+			iserr: true,
+		},
+		{
+			addr:  "{Dog}/nickname/foo",
+			iserr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.addr, func(t *testing.T) {
+			ptr, err := ResolveAddrPointer(val, MustParseAddr(tt.addr))
+			if tt.iserr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, ptr)
+		})
+	}
+}
+
+func TestJSONValueSchemaType(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    interface{}
+		variants []string
+		expect   string
+		err      bool
+	}{
+		{name: "whole number as integer", value: float64(1), variants: []string{"integer", "number"}, expect: "integer"},
+		{name: "fraction as number", value: 1.5, variants: []string{"integer", "number"}, expect: "number"},
+		{name: "fraction falls back to integer", value: 1.5, variants: []string{"integer", "string"}, expect: "integer"},
+		{name: "whole number falls back to number", value: float64(1), variants: []string{"number", "string"}, expect: "number"},
+		{name: "string", value: "a", variants: []string{"integer", "string"}, expect: "string"},
+		{name: "no match", value: true, variants: []string{"integer", "string"}, err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			variants := map[string]*Property{}
+			for _, v := range tt.variants {
+				variants[v] = &Property{}
+			}
+			actual, err := jsonValueSchemaType(tt.value, variants)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, actual)
+		})
+	}
+}
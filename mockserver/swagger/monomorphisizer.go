@@ -1,65 +1,315 @@
 package swagger
 
-import "sort"
-
-func Monomorphization(prop *Property) []Property {
-	var monomorph func(p *Property) []Property
-	monomorph = func(p *Property) []Property {
-		var result []Property
-		switch {
-		case p.Element != nil:
-			elements := monomorph(p.Element)
-			for _, elem := range elements {
-				elem := elem
-				np := *p
-				np.Element = &elem
-				result = append(result, np)
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+type MonomorphizationOption struct {
+	// Keep the variants of the polymorphic array element as is, instead of expanding them to different instances.
+	// This is used together with the SynthesizerOption.ArrayElementVariants.
+	KeepArrayElementVariants bool
+
+	// Selector is a JSON value (in form of a JSON merge patch) that the wanted instances are expected to match.
+	// It is used to prune the variants of the discriminated polymorphic properties before enumeration, by keeping only
+	// the variants whose discriminator value is specified. An array can also be represented as an object keyed by the
+	// element index, as is built from the JSON patch paths.
+	Selector interface{}
+
+	// Variants fixes the variant choices of the polymorphic properties, each is the address of the chosen variant
+	// (e.g. "properties/source{AzureBlob}"). The other variants of the same polymorphic property are pruned.
+	Variants []PropertyAddr
+
+	// MaxInstances limits the number of instances to enumerate. 0 means no limit.
+	MaxInstances int
+}
+
+// Monomorphizer enumerates the monomorphized instances of a property lazily.
+// Each instance is identified by its index, which is decoded into the variant choice of each polymorphic property,
+// so that none of the instances is materialized until it is asked for.
+type Monomorphizer struct {
+	root   *Property
+	opt    MonomorphizationOption
+	counts map[*Property]int
+	total  int
+	next   int
+
+	// matched records the indexes of the Variants option that have matched any variant during pruning.
+	matched map[int]bool
+}
+
+// NewMonomorphizer creates a Monomorphizer for the property. The property is not modified.
+// It errors if any address of the Variants option matches no variant of the (pruned) property.
+func NewMonomorphizer(prop *Property, opt *MonomorphizationOption) (*Monomorphizer, error) {
+	if opt == nil {
+		opt = &MonomorphizationOption{}
+	}
+	m := &Monomorphizer{
+		root:    prop,
+		opt:     *opt,
+		counts:  map[*Property]int{},
+		matched: map[int]bool{},
+	}
+	if opt.Selector != nil || len(opt.Variants) != 0 {
+		var vals []interface{}
+		if opt.Selector != nil {
+			vals = append(vals, opt.Selector)
+		}
+		m.root = m.prune(prop, vals)
+	}
+	for i, addr := range opt.Variants {
+		if !m.matched[i] {
+			return nil, fmt.Errorf("variant address %q matches no variant of the polymorphic properties", addr.String())
+		}
+	}
+	m.total = m.count(m.root)
+	return m, nil
+}
+
+// Total returns the total number of the instances (after pruning), regardless of the MaxInstances.
+// It saturates at math.MaxInt.
+func (m *Monomorphizer) Total() int {
+	return m.total
+}
+
+// Len returns the number of the instances to enumerate, which is bounded by the MaxInstances.
+func (m *Monomorphizer) Len() int {
+	if m.opt.MaxInstances > 0 && m.total > m.opt.MaxInstances {
+		return m.opt.MaxInstances
+	}
+	return m.total
+}
+
+// Instance returns the i-th instance, where i is in the range of [0, Len()).
+func (m *Monomorphizer) Instance(i int) Property {
+	return m.decode(m.root, i)
+}
+
+// Next returns the next instance, or false if the enumeration is over.
+func (m *Monomorphizer) Next() (Property, bool) {
+	if m.next >= m.Len() {
+		return Property{}, false
+	}
+	prop := m.Instance(m.next)
+	m.next++
+	return prop, true
+}
+
+func (m *Monomorphizer) keepAsIs(p *Property) bool {
+	return m.opt.KeepArrayElementVariants && p.isPolymorphicArray()
+}
+
+func (m *Monomorphizer) count(p *Property) int {
+	n := 1
+	switch {
+	case p.Element != nil && m.keepAsIs(p):
+	case p.Element != nil:
+		n = m.count(p.Element)
+	case p.Children != nil:
+		if len(p.Children) == 0 {
+			// empty object
+			break
+		}
+		// Children without any instance are omitted, unless all of them are so.
+		n = 0
+		for _, child := range p.Children {
+			c := m.count(child)
+			if c == 0 {
+				continue
 			}
-		case p.Children != nil:
-			if len(p.Children) == 0 {
-				// empty object
-				np := *p
-				return []Property{np}
+			if n == 0 {
+				n = c
+				continue
 			}
+			n = saturatingMul(n, c)
+		}
+	case p.Variant != nil:
+		n = 0
+		for _, variant := range p.Variant {
+			n = saturatingAdd(n, m.count(variant))
+		}
+	}
+	m.counts[p] = n
+	return n
+}
 
-			m := map[string][]Property{}
-			var keys []string
-			for k := range p.Children {
-				keys = append(keys, k)
+// decode builds the i-th instance of the property. The index is decoded in the mixed radix of the instance count of
+// each child (in the sorted key order, the first one varies fastest), or by the consecutive ranges of the instance
+// count of each variant (in the sorted key order).
+func (m *Monomorphizer) decode(p *Property, i int) Property {
+	np := *p
+	switch {
+	case p.Element != nil && m.keepAsIs(p):
+	case p.Element != nil:
+		elem := m.decode(p.Element, i)
+		np.Element = &elem
+	case p.Children != nil:
+		if len(p.Children) == 0 {
+			break
+		}
+		np.Children = map[string]*Property{}
+		for _, k := range sortedKeys(p.Children) {
+			child := p.Children[k]
+			c := m.counts[child]
+			if c == 0 {
+				continue
 			}
-			sort.Strings(keys)
-			for _, k := range keys {
-				m[k] = monomorph(p.Children[k])
+			instance := m.decode(child, i%c)
+			np.Children[k] = &instance
+			i /= c
+		}
+	case p.Variant != nil:
+		for _, k := range sortedKeys(p.Variant) {
+			variant := p.Variant[k]
+			c := m.counts[variant]
+			if i < c {
+				instance := m.decode(variant, i)
+				np.Variant = map[string]*Property{
+					k: &instance,
+				}
+				break
 			}
-			for _, mm := range CatesianProductMap(m) {
-				np := *p
-				np.Children = map[string]*Property{}
-				for k, child := range mm {
-					child := child
-					np.Children[k] = &child
+			i -= c
+		}
+	}
+	return np
+}
+
+// prune returns a copy of the property, whose variants are pruned by the Variants option, or otherwise (for the discriminated ones)
+// by the selector values applied to it.
+// The sub-trees that are not affected are shared with the original property.
+func (m *Monomorphizer) prune(p *Property, vals []interface{}) *Property {
+	if len(vals) == 0 && len(m.opt.Variants) == 0 {
+		return p
+	}
+	np := *p
+	switch {
+	case p.Element != nil && m.keepAsIs(p):
+		np.Element = m.prune(p.Element, nil)
+	case p.Element != nil:
+		var elemVals []interface{}
+		for _, v := range vals {
+			switch v := v.(type) {
+			case []interface{}:
+				elemVals = append(elemVals, v...)
+			case map[string]interface{}:
+				for k, ev := range v {
+					if _, err := strconv.Atoi(k); err == nil {
+						elemVals = append(elemVals, ev)
+					}
 				}
-				result = append(result, np)
 			}
-		case p.Variant != nil:
-			keys := make([]string, 0, len(p.Variant))
-			for k := range p.Variant {
-				keys = append(keys, k)
+		}
+		np.Element = m.prune(p.Element, elemVals)
+	case p.Children != nil:
+		np.Children = make(map[string]*Property, len(p.Children))
+		for k, child := range p.Children {
+			var childVals []interface{}
+			for _, v := range vals {
+				if obj, ok := v.(map[string]interface{}); ok && obj[k] != nil {
+					childVals = append(childVals, obj[k])
+				}
 			}
-			sort.Strings(keys)
-			for _, k := range keys {
-				for _, variant := range monomorph(p.Variant[k]) {
-					variant := variant
-					np := *p
-					np.Variant = map[string]*Property{
-						k: &variant,
+			np.Children[k] = m.prune(child, childVals)
+		}
+	case p.Variant != nil:
+		variants := m.fixedVariants(p)
+		if variants == nil {
+			variants = p.Variant
+		}
+		if len(variants) == len(p.Variant) && p.VariantKind == PropertyVariantKindDiscriminator {
+			// Only prune when every selector value specifies the discriminator value.
+			constrained := true
+			selected := map[string]*Property{}
+			for _, v := range vals {
+				obj, ok := v.(map[string]interface{})
+				if !ok {
+					constrained = false
+					break
+				}
+				var found bool
+				for k, variant := range p.Variant {
+					dv, ok := obj[variant.Discriminator]
+					if !ok {
+						continue
 					}
-					result = append(result, np)
+					found = true
+					if dv == variant.DiscriminatorValue {
+						selected[k] = variant
+					}
+				}
+				if !found {
+					constrained = false
+					break
+				}
+			}
+			if constrained && len(selected) != 0 {
+				variants = selected
+			}
+		}
+		np.Variant = make(map[string]*Property, len(variants))
+		for k, variant := range variants {
+			np.Variant[k] = m.prune(variant, vals)
+		}
+	}
+	return &np
+}
+
+// fixedVariants returns the variants of the property that are fixed by the Variants option, or nil if none is fixed.
+func (m *Monomorphizer) fixedVariants(p *Property) map[string]*Property {
+	var fixed map[string]*Property
+	for k, variant := range p.Variant {
+		for i, addr := range m.opt.Variants {
+			if variant.addr.Equal(addr) {
+				if fixed == nil {
+					fixed = map[string]*Property{}
 				}
+				fixed[k] = variant
+				m.matched[i] = true
 			}
-		default:
-			result = []Property{*p}
 		}
-		return result
 	}
-	return monomorph(prop)
+	return fixed
+}
+
+// Monomorphization returns all the monomorphized instances of the property (bounded by the MaxInstances).
+func Monomorphization(prop *Property, opt *MonomorphizationOption) ([]Property, error) {
+	m, err := NewMonomorphizer(prop, opt)
+	if err != nil {
+		return nil, err
+	}
+	var result []Property
+	for {
+		instance, ok := m.Next()
+		if !ok {
+			break
+		}
+		result = append(result, instance)
+	}
+	return result, nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
 }
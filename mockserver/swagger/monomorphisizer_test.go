@@ -309,7 +309,193 @@ func TestMonomorphization(t *testing.T) {
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			require.Equal(t, tt.expect, Monomorphization(&tt.input))
+			instances, err := Monomorphization(&tt.input, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, instances)
 		})
 	}
 }
+
+func TestMonomorphizer(t *testing.T) {
+	newPolyProp := func(name string) *Property {
+		prop := &Property{
+			addr:    MustParseAddr(name),
+			Variant: map[string]*Property{},
+		}
+		for _, v := range []string{"V1", "V2", "V3"} {
+			prop.Variant[v] = &Property{
+				addr:               MustParseAddr(name + "{" + v + "}"),
+				Discriminator:      "kind",
+				DiscriminatorValue: v,
+			}
+		}
+		return prop
+	}
+	input := Property{
+		addr: RootAddr,
+		Children: map[string]*Property{
+			"p1": newPolyProp("p1"),
+			"p2": newPolyProp("p2"),
+			"p3": {
+				addr:    MustParseAddr("p3"),
+				Element: newPolyProp("p3/*"),
+			},
+		},
+	}
+	all, err := Monomorphization(&input, nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		opt       *MonomorphizationOption
+		total     int
+		len       int
+		variantOf map[string]string
+	}{
+		{
+			name:  "No option",
+			total: 27,
+			len:   27,
+		},
+		{
+			name:  "Capped",
+			opt:   &MonomorphizationOption{MaxInstances: 10},
+			total: 27,
+			len:   10,
+		},
+		{
+			name: "Selector prunes object",
+			opt: &MonomorphizationOption{
+				Selector: map[string]interface{}{
+					"p1": map[string]interface{}{"kind": "V2"},
+				},
+			},
+			total:     9,
+			len:       9,
+			variantOf: map[string]string{"p1": "V2"},
+		},
+		{
+			name: "Selector prunes array element",
+			opt: &MonomorphizationOption{
+				Selector: map[string]interface{}{
+					"p2": map[string]interface{}{"kind": "V3"},
+					"p3": []interface{}{
+						map[string]interface{}{"kind": "V1"},
+					},
+				},
+			},
+			total:     3,
+			len:       3,
+			variantOf: map[string]string{"p2": "V3", "p3": "V1"},
+		},
+		{
+			name: "Selector prunes array element in index form",
+			opt: &MonomorphizationOption{
+				Selector: map[string]interface{}{
+					"p3": map[string]interface{}{
+						"0": map[string]interface{}{"kind": "V1"},
+					},
+				},
+			},
+			total:     9,
+			len:       9,
+			variantOf: map[string]string{"p3": "V1"},
+		},
+		{
+			name: "Variants",
+			opt: &MonomorphizationOption{
+				Variants: []PropertyAddr{
+					MustParseAddr("p1{V3}"),
+					MustParseAddr("p3/*{V2}"),
+				},
+			},
+			total:     3,
+			len:       3,
+			variantOf: map[string]string{"p1": "V3", "p3": "V2"},
+		},
+		{
+			name: "Variants take precedence over selector",
+			opt: &MonomorphizationOption{
+				Selector: map[string]interface{}{
+					"p1": map[string]interface{}{"kind": "V2"},
+					"p2": map[string]interface{}{"kind": "V2"},
+				},
+				Variants: []PropertyAddr{
+					MustParseAddr("p1{V1}"),
+				},
+			},
+			total:     3,
+			len:       3,
+			variantOf: map[string]string{"p1": "V1", "p2": "V2"},
+		},
+		{
+			name: "Selector without discriminator value",
+			opt: &MonomorphizationOption{
+				Selector: map[string]interface{}{
+					"p1": map[string]interface{}{"foo": "bar"},
+				},
+			},
+			total: 27,
+			len:   27,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMonomorphizer(&input, tt.opt)
+			require.NoError(t, err)
+			require.Equal(t, tt.total, m.Total())
+			require.Equal(t, tt.len, m.Len())
+			var instances []Property
+			for {
+				instance, ok := m.Next()
+				if !ok {
+					break
+				}
+				instances = append(instances, instance)
+			}
+			require.Len(t, instances, tt.len)
+			if tt.opt == nil || tt.opt.Selector == nil && len(tt.opt.Variants) == 0 {
+				require.Equal(t, all[:tt.len], instances)
+			}
+			for _, instance := range instances {
+				for k, v := range tt.variantOf {
+					prop := instance.Children[k]
+					if prop.Element != nil {
+						prop = prop.Element
+					}
+					require.Contains(t, prop.Variant, v)
+				}
+			}
+		})
+	}
+}
+
+func TestMonomorphizerUnmatchedVariants(t *testing.T) {
+	input := Property{
+		addr: RootAddr,
+		Children: map[string]*Property{
+			"p1": {
+				addr: MustParseAddr("p1"),
+				Variant: map[string]*Property{
+					"V1": {addr: MustParseAddr("p1{V1}"), Discriminator: "kind", DiscriminatorValue: "V1"},
+					"V2": {addr: MustParseAddr("p1{V2}"), Discriminator: "kind", DiscriminatorValue: "V2"},
+				},
+			},
+		},
+	}
+	_, err := NewMonomorphizer(&input, &MonomorphizationOption{
+		Variants: []PropertyAddr{
+			MustParseAddr("p1{V1}"),
+			MustParseAddr("p1{V3}"),
+		},
+	})
+	require.ErrorContains(t, err, `"p1{V3}"`)
+
+	_, err = NewMonomorphizer(&input, &MonomorphizationOption{
+		Variants: []PropertyAddr{
+			MustParseAddr("p2{V1}"),
+		},
+	})
+	require.ErrorContains(t, err, `"p2{V1}"`)
+}
@@ -2,6 +2,7 @@ package swagger
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/go-openapi/jsonreference"
 	"github.com/go-openapi/spec"
@@ -48,6 +49,20 @@ func (model *RootModelInfo) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// PropertyVariantKind tells how the variants of a polymorphic property are derived.
+type PropertyVariantKind int
+
+const (
+	// The variants are the models that inherit from the base model with a discriminator, keyed by the discriminator value.
+	PropertyVariantKindDiscriminator PropertyVariantKind = iota
+	// The variants are the types of a schema that has multiple types, keyed by the type name.
+	PropertyVariantKindType
+	// The variants are the oneOf schemas, keyed by the schema title or index.
+	PropertyVariantKindOneOf
+	// The variants are the anyOf schemas, keyed by the schema title or index.
+	PropertyVariantKindAnyOf
+)
+
 type Property struct {
 	Schema *spec.Schema
 
@@ -57,8 +72,12 @@ type Property struct {
 	// The property address starting from the main model.
 	addr PropertyAddr
 
-	// The resolved refs (normalized) along the way to this property, which is used to avoid cyclic reference.
-	visitedRefs map[string]bool
+	// The client-visible property address starting from the main model, which honors the x-ms-client-name and x-ms-client-flatten.
+	// It is nil if it is the same as addr.
+	clientAddr PropertyAddr
+
+	// The resolved refs (normalized) along the way to this property, together with their visited times, which is used to bound cyclic reference.
+	visitedRefs map[string]int
 
 	// The ref (normalized) that points to the concrete schema of this property.
 	// E.g. prop1's schema is "schema1", which refs "schema2", which refs "schema3".
@@ -73,6 +92,9 @@ type Property struct {
 	// This only applies to property that is a variant schema.
 	DiscriminatorValue string
 
+	// Meta is the metadata of this property, collected from its schema definition.
+	Meta PropertyMeta
+
 	// Children represents the child properties of an object
 	// At most one of Children, Element and Variant is non nil
 	Children map[string]*Property
@@ -84,6 +106,83 @@ type Property struct {
 	// Variant represents the current property is a polymorphic schema, which is then expanded to multiple variant schemas
 	// At most one of Children, Element and Variant is non nil
 	Variant map[string]*Property
+
+	// VariantKind indicates how the Variant is derived.
+	// This only applies to property that has Variant.
+	VariantKind PropertyVariantKind
+}
+
+// PropertyMeta is the metadata of a property, which is mainly used for auditing the mapping results.
+type PropertyMeta struct {
+	// Required indicates whether this property is required by its parent object.
+	Required    bool          `json:"required,omitempty"`
+	ReadOnly    bool          `json:"read_only,omitempty"`
+	Secret      bool          `json:"secret,omitempty"`
+	Mutability  []string      `json:"mutability,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+// overlay returns a copy of the meta, overlaid by the non-empty metadata defined in the schema.
+func (meta PropertyMeta) overlay(sch *spec.Schema) PropertyMeta {
+	if sch == nil {
+		return meta
+	}
+	if sch.ReadOnly {
+		meta.ReadOnly = true
+	}
+	if v, ok := sch.Extensions.GetBool("x-ms-secret"); ok && v {
+		meta.Secret = true
+	}
+	if v, ok := sch.Extensions.GetStringSlice("x-ms-mutability"); ok && len(v) != 0 {
+		meta.Mutability = v
+	}
+	if len(sch.Enum) != 0 {
+		meta.Enum = sch.Enum
+	}
+	if sch.Description != "" {
+		meta.Description = sch.Description
+	}
+	return meta
+}
+
+// ClientAddr returns the client-visible address of the property, as is referred by the SDKs.
+func (prop *Property) ClientAddr() PropertyAddr {
+	if prop.clientAddr == nil {
+		return prop.addr
+	}
+	return prop.clientAddr
+}
+
+// jsonValuePos returns the position of a JSON value that is of this property.
+func (prop *Property) jsonValuePos() *JSONValuePos {
+	return &JSONValuePos{
+		Addr:       prop.addr,
+		ClientAddr: prop.ClientAddr(),
+		Meta:       prop.Meta,
+		Ref:        prop.ref.Ref,
+		RootModel:  prop.RootModel,
+	}
+}
+
+// LeafPositions returns the positions of the primitive leaf properties of the property tree (including all the variants), sorted by their addresses.
+// The properties that hit a circular reference during the expansion are not included.
+func (prop *Property) LeafPositions() []*JSONValuePos {
+	var out []*JSONValuePos
+	prop.Walk(func(p *Property) bool {
+		if p.Children != nil || p.Element != nil || p.Variant != nil || p.Schema == nil || len(p.Schema.Type) != 1 {
+			return true
+		}
+		switch p.Schema.Type[0] {
+		case "string", "file", "integer", "number", "boolean":
+			out = append(out, p.jsonValuePos())
+		}
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Addr.String() < out[j].Addr.String()
+	})
+	return out
 }
 
 // PropWalkFunc is invoked during the property tree walking. If it returns false, it will stop walking at that property.
@@ -131,14 +230,19 @@ func (prop Property) String() string {
 
 // IsMono tests whether the Property is monomorphisized
 func (prop *Property) IsMono() bool {
+	return prop.isMono(false)
+}
+
+// isMono tests whether the Property is monomorphisized. If allowArrayElementVariants is true, the polymorphic array elements are regarded as monomorphisized.
+func (prop *Property) isMono(allowArrayElementVariants bool) bool {
 	if prop == nil {
 		return true
 	}
-	if !prop.Element.IsMono() {
+	if !(allowArrayElementVariants && prop.isPolymorphicArray()) && !prop.Element.isMono(allowArrayElementVariants) {
 		return false
 	}
 	for _, child := range prop.Children {
-		if !child.IsMono() {
+		if !child.isMono(allowArrayElementVariants) {
 			return false
 		}
 	}
@@ -146,9 +250,14 @@ func (prop *Property) IsMono() bool {
 		return false
 	}
 	for _, variant := range prop.Variant {
-		if !variant.IsMono() {
+		if !variant.isMono(allowArrayElementVariants) {
 			return false
 		}
 	}
 	return true
 }
+
+// isPolymorphicArray tests whether the Property is an array whose element is polymorphic.
+func (prop *Property) isPolymorphicArray() bool {
+	return prop.Element != nil && len(prop.Element.Variant) != 0 && prop.Schema != nil && SchemaIsArray(prop.Schema)
+}
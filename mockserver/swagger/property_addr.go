@@ -15,6 +15,8 @@ const (
 	variantCloseRune      = '}'
 	escapeRune            = '\\'
 	indexRune             = '*'
+	keyOpenRune           = '['
+	keyCloseRune          = ']'
 )
 
 type PropertyAddr []PropertyAddrStep
@@ -29,6 +31,8 @@ type PropertyAddrStep struct {
 	Type    PropertyAddrStepType
 	Value   string
 	Variant string
+	// Key qualifies an index step with the concrete array index or map key (e.g. "*[1]"), which is only used to address a concrete JSON value.
+	Key string
 }
 
 func (step PropertyAddrStep) String() string {
@@ -36,6 +40,9 @@ func (step PropertyAddrStep) String() string {
 	switch step.Type {
 	case PropertyAddrStepTypeIndex:
 		v = string(indexRune)
+		if step.Key != "" {
+			v += string(keyOpenRune) + step.Key + string(keyCloseRune)
+		}
 	case PropertyAddrStepTypeProp:
 		v = escapePropValue(step.Value)
 	default:
@@ -116,8 +123,10 @@ func ParseAddr(input string) (*PropertyAddr, error) {
 		addr        PropertyAddr
 		s           scanner.Scanner
 		inVariant   bool
+		inKey       bool
 		stepValue   string
 		stepVariant string
+		stepKey     string
 	)
 
 	s.Init(strings.NewReader(input))
@@ -126,6 +135,23 @@ func ParseAddr(input string) (*PropertyAddr, error) {
 		err = errors.Join(err, errors.New(msg))
 	}
 	for tk := s.Next(); ; tk = s.Next() {
+		if inKey {
+			switch tk {
+			case scanner.EOF:
+				return nil, fmt.Errorf("unclosed index key")
+			case keyCloseRune:
+				if pk := s.Peek(); pk != delimRune && pk != variantOpenRune && pk != scanner.EOF {
+					return nil, fmt.Errorf(`index key ends with additional tokens`)
+				}
+				if stepKey == "" {
+					return nil, fmt.Errorf(`empty index key`)
+				}
+				inKey = false
+			default:
+				stepKey += string(tk)
+			}
+			continue
+		}
 		switch tk {
 		case delimRune, scanner.EOF:
 			if inVariant {
@@ -135,12 +161,13 @@ func ParseAddr(input string) (*PropertyAddr, error) {
 					return nil, fmt.Errorf("both step value and step variant is empty")
 				}
 				if stepValue == string(indexRune) {
-					addr = append(addr, PropertyAddrStep{Type: PropertyAddrStepTypeIndex, Variant: stepVariant})
+					addr = append(addr, PropertyAddrStep{Type: PropertyAddrStepTypeIndex, Variant: stepVariant, Key: stepKey})
 				} else {
 					addr = append(addr, PropertyAddrStep{Type: PropertyAddrStepTypeProp, Value: stepValue, Variant: stepVariant})
 				}
 				stepValue = ""
 				stepVariant = ""
+				stepKey = ""
 			}
 			if tk == scanner.EOF {
 				return &addr, err
@@ -157,6 +184,16 @@ func ParseAddr(input string) (*PropertyAddr, error) {
 			default:
 				return nil, fmt.Errorf("invalid escape %q", `\`+string(pk))
 			}
+		case keyOpenRune:
+			if !inVariant && stepValue == string(indexRune) && stepKey == "" {
+				inKey = true
+				continue
+			}
+			if inVariant {
+				stepVariant += string(tk)
+			} else {
+				stepValue += string(tk)
+			}
 		case variantOpenRune:
 			if inVariant {
 				stepVariant += string(tk)
@@ -166,6 +166,54 @@ func TestParseAddr(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "a/*[1]/b",
+			expect: PropertyAddr{
+				{
+					Type:  PropertyAddrStepTypeProp,
+					Value: "a",
+				},
+				{
+					Type: PropertyAddrStepTypeIndex,
+					Key:  "1",
+				},
+				{
+					Type:  PropertyAddrStepTypeProp,
+					Value: "b",
+				},
+			},
+		},
+		{
+			input: "*[KEY/1]{Foo}",
+			expect: PropertyAddr{
+				{
+					Type:    PropertyAddrStepTypeIndex,
+					Key:     "KEY/1",
+					Variant: "Foo",
+				},
+			},
+		},
+		{
+			input: "a[1]",
+			expect: PropertyAddr{
+				{
+					Type:  PropertyAddrStepTypeProp,
+					Value: "a[1]",
+				},
+			},
+		},
+		{
+			input: `*[]`,
+			iserr: true,
+		},
+		{
+			input: `*[1`,
+			iserr: true,
+		},
+		{
+			input: `*[1]a`,
+			iserr: true,
+		},
 		{
 			input: `a\a`,
 			iserr: true,
@@ -218,6 +266,16 @@ func TestPropertyAddrString(t *testing.T) {
 			},
 			expect: "*",
 		},
+		{
+			input: PropertyAddr{
+				{
+					Type:    PropertyAddrStepTypeIndex,
+					Key:     "1",
+					Variant: "Foo",
+				},
+			},
+			expect: "*[1]{Foo}",
+		},
 		{
 			input: PropertyAddr{
 				{
@@ -1,13 +1,12 @@
 package swagger
 
 import (
-	"bytes"
 	"encoding/base64"
-	"encoding/binary"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
-
-	"github.com/rickb777/date/period"
 )
 
 // We use following command to find all the used formats in Swagger (mgmt plane):
@@ -37,6 +36,22 @@ import (
 // uri
 // url
 // uuid
+//
+// Additionally, following formats are supported as they are commonly used in the data plane:
+// ipv4
+// ipv6
+// cidr
+
+// ArmIDResource describes an allowed resource of an "arm-id" formatted string, as defined in "x-ms-arm-id-details".
+type ArmIDResource struct {
+	// Type is the resource type, e.g. "Microsoft.Network/virtualNetworks/subnets"
+	Type string
+	// Scopes are the allowed scopes of the resource, e.g. "ResourceGroup", "Subscription", "ManagementGroup", "Tenant", "Extension".
+	// By default, it is "ResourceGroup".
+	Scopes []string
+}
+
+const rndSubscriptionId = "00000000-0000-0000-0000-000000000000"
 
 type Rnd struct {
 	rawString  string
@@ -75,29 +90,37 @@ func NewRnd(opt *RndOption) Rnd {
 func (rnd Rnd) genString(format string) string {
 	switch format {
 	case "arm-id":
-		return "/subscriptions/00000000-0000-0000-000000000000/resourceGroups/" + rnd.rawString
-	case "base64url", "byte":
+		return rnd.genArmID(nil)
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString([]byte(rnd.rawString))
+	case "byte":
 		return base64.StdEncoding.EncodeToString([]byte(rnd.rawString))
 	case "binary":
-		buf := new(bytes.Buffer)
-		err := binary.Write(buf, binary.LittleEndian, rnd.rawInteger)
-		if err != nil {
-			panic(fmt.Sprintf("binary.Write failed: %v", err))
-		}
-		return buf.String()
+		return rnd.rawString
 	case "date":
 		return rnd.time.Format("2006-01-02")
 	case "date-time":
 		return rnd.time.Format(time.RFC3339)
 	case "date-time-rfc1123":
-		return rnd.time.Format(time.RFC1123)
+		return rnd.time.UTC().Format(http.TimeFormat)
+	case "decimal":
+		return strconv.FormatFloat(rnd.rawNumber, 'f', -1, 64)
 	case "duration":
-		p, _ := period.NewOf(time.Duration(rnd.rawInteger) * time.Hour)
-		return p.String()
+		// The day part makes the duration unique, while the others make it cover all the designators
+		n := rnd.rawInteger
+		return fmt.Sprintf("P%dDT%dH%dM%dS", n, n%24, n%60, n%60)
 	case "email":
 		return rnd.rawString + "@foo.com"
 	case "file", "password":
 		return rnd.rawString
+	case "ipv4":
+		n := rnd.rawInteger
+		return fmt.Sprintf("10.%d.%d.%d", (n>>16)&0xff, (n>>8)&0xff, n&0xff)
+	case "ipv6":
+		return fmt.Sprintf("fd00::%x", rnd.rawInteger)
+	case "cidr":
+		n := rnd.rawInteger
+		return fmt.Sprintf("10.%d.%d.0/24", (n>>8)&0xff, n&0xff)
 	case "time":
 		return rnd.time.Format("15:04:05")
 	case "uri", "url":
@@ -115,6 +138,43 @@ func (rnd Rnd) genString(format string) string {
 	}
 }
 
+// genArmID generates a resource id of the first allowed resource, where the scope name segment is the raw string, and each resource name segment
+// is the raw string suffixed by its 1-based index (e.g. "b-1"), so that the segments are distinct from each other.
+// If there is no allowed resource, a resource group id is generated.
+func (rnd Rnd) genArmID(allowed []ArmIDResource) string {
+	if len(allowed) == 0 {
+		return "/subscriptions/" + rndSubscriptionId + "/resourceGroups/" + rnd.rawString
+	}
+	res := allowed[0]
+
+	scope := "ResourceGroup"
+	if len(res.Scopes) != 0 {
+		scope = res.Scopes[0]
+	}
+	var id string
+	switch strings.ToLower(scope) {
+	case "tenant":
+		id = ""
+	case "subscription":
+		id = "/subscriptions/" + rndSubscriptionId
+	case "managementgroup":
+		id = "/providers/Microsoft.Management/managementGroups/" + rnd.rawString
+	default:
+		// Including the extension resources, which are put upon a resource group
+		id = "/subscriptions/" + rndSubscriptionId + "/resourceGroups/" + rnd.rawString
+	}
+
+	segs := strings.Split(res.Type, "/")
+	if len(segs) < 2 {
+		return id
+	}
+	id += "/providers/" + segs[0]
+	for i, t := range segs[1:] {
+		id += "/" + t + "/" + rnd.rawString + "-" + strconv.Itoa(i+1)
+	}
+	return id
+}
+
 func (rnd *Rnd) NextString(format string) string {
 	switch format {
 	case "arm-id":
@@ -122,19 +182,24 @@ func (rnd *Rnd) NextString(format string) string {
 	case "base64url", "byte":
 		rnd.updateRawString()
 	case "binary":
-		rnd.updateRawInteger()
+		rnd.updateRawString()
 	case "date":
 		rnd.nextRawTime(time.Hour * time.Duration(24))
 	case "date-time":
 		rnd.nextRawTime(time.Hour)
 	case "date-time-rfc1123":
+		// RFC1123 has a resolution of second
 		rnd.nextRawTime(time.Hour)
+	case "decimal":
+		rnd.updateRawNumber()
 	case "duration":
 		rnd.updateRawInteger()
 	case "email":
 		rnd.updateRawString()
 	case "file", "password":
 		rnd.updateRawString()
+	case "ipv4", "ipv6", "cidr":
+		rnd.updateRawInteger()
 	case "time":
 		rnd.nextRawTime(time.Hour)
 	case "uri", "url":
@@ -147,9 +212,17 @@ func (rnd *Rnd) NextString(format string) string {
 	return rnd.genString(format)
 }
 
+// NextArmID generates the next resource id that is of one of the allowed resources.
+func (rnd *Rnd) NextArmID(allowed []ArmIDResource) string {
+	rnd.updateRawString()
+	return rnd.genArmID(allowed)
+}
+
 func (rnd Rnd) genInteger(format string) int64 {
 	switch format {
-	case "int32", "int64", "unixtime":
+	case "unixtime":
+		return rnd.time.Unix()
+	case "int32", "int64":
 		return rnd.rawInteger
 	default:
 		return rnd.rawInteger
@@ -157,7 +230,12 @@ func (rnd Rnd) genInteger(format string) int64 {
 }
 
 func (rnd *Rnd) NextInteger(format string) int64 {
-	rnd.updateRawInteger()
+	switch format {
+	case "unixtime":
+		rnd.nextRawTime(time.Hour)
+	default:
+		rnd.updateRawInteger()
+	}
 	return rnd.genInteger(format)
 }
 
@@ -1,6 +1,7 @@
 package swagger
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
@@ -49,6 +50,55 @@ func TestRnd(t *testing.T) {
 			again:  true,
 			expect: 2.5,
 		},
+		{
+			typ:    "string",
+			format: "arm-id",
+			expect: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/b",
+		},
+		{
+			typ:    "string",
+			format: "arm-id",
+			again:  true,
+			expect: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/c",
+		},
+		{
+			typ:    "string",
+			format: "base64url",
+			expect: "Yg",
+		},
+		{
+			typ:     "string",
+			format:  "base64url",
+			initRnd: &Rnd{rawString: "n>>"},
+			expect:  "bj4_",
+		},
+		{
+			typ:    "string",
+			format: "byte",
+			expect: "Yg==",
+		},
+		{
+			typ:    "string",
+			format: "binary",
+			expect: "b",
+		},
+		{
+			typ:    "string",
+			format: "binary",
+			again:  true,
+			expect: "c",
+		},
+		{
+			typ:    "string",
+			format: "cidr",
+			expect: "10.0.1.0/24",
+		},
+		{
+			typ:     "string",
+			format:  "cidr",
+			initRnd: &Rnd{rawInteger: 0x1ff},
+			expect:  "10.2.0.0/24",
+		},
 		{
 			typ:    "string",
 			format: "date",
@@ -74,13 +124,13 @@ func TestRnd(t *testing.T) {
 		{
 			typ:    "string",
 			format: "date-time-rfc1123",
-			expect: initRnd.time.Add(time.Hour).Format(time.RFC1123),
+			expect: initRnd.time.Add(time.Hour).UTC().Format(http.TimeFormat),
 		},
 		{
 			typ:    "string",
 			format: "date-time-rfc1123",
 			again:  true,
-			expect: initRnd.time.Add(time.Duration(2) * time.Hour).Format(time.RFC1123),
+			expect: initRnd.time.Add(time.Duration(2) * time.Hour).UTC().Format(http.TimeFormat),
 		},
 		{
 			typ:    "number",
@@ -104,16 +154,27 @@ func TestRnd(t *testing.T) {
 			again:  true,
 			expect: 2.5,
 		},
+		{
+			typ:    "string",
+			format: "decimal",
+			expect: "1.5",
+		},
+		{
+			typ:    "string",
+			format: "decimal",
+			again:  true,
+			expect: "2.5",
+		},
 		{
 			typ:    "string",
 			format: "duration",
-			expect: "PT1H",
+			expect: "P1DT1H1M1S",
 		},
 		{
 			typ:    "string",
 			format: "duration",
 			again:  true,
-			expect: "PT2H",
+			expect: "P2DT2H2M2S",
 		},
 		{
 			typ:    "string",
@@ -170,6 +231,28 @@ func TestRnd(t *testing.T) {
 			again:  true,
 			expect: int64(2),
 		},
+		{
+			typ:    "string",
+			format: "ipv4",
+			expect: "10.0.0.1",
+		},
+		{
+			typ:     "string",
+			format:  "ipv4",
+			initRnd: &Rnd{rawInteger: 0x1ffff},
+			expect:  "10.2.0.0",
+		},
+		{
+			typ:    "string",
+			format: "ipv6",
+			expect: "fd00::1",
+		},
+		{
+			typ:    "string",
+			format: "ipv6",
+			again:  true,
+			expect: "fd00::2",
+		},
 		{
 			typ:    "string",
 			format: "password",
@@ -195,13 +278,13 @@ func TestRnd(t *testing.T) {
 		{
 			typ:    "integer",
 			format: "unixtime",
-			expect: int64(1),
+			expect: initRnd.time.Add(time.Hour).Unix(),
 		},
 		{
 			typ:    "integer",
 			format: "unixtime",
 			again:  true,
-			expect: int64(2),
+			expect: initRnd.time.Add(time.Duration(2) * time.Hour).Unix(),
 		},
 		{
 			typ:    "string",
@@ -289,3 +372,51 @@ func TestRnd_NextRawStringCarray(t *testing.T) {
 	}
 	require.Equal(t, "aa", rnd.rawString)
 }
+
+func TestRnd_NextArmID(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed []ArmIDResource
+		expect  string
+	}{
+		{
+			name:   "no allowed resource",
+			expect: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/b",
+		},
+		{
+			name: "resource group scope",
+			allowed: []ArmIDResource{
+				{Type: "Microsoft.Network/virtualNetworks/subnets"},
+				{Type: "Microsoft.Compute/virtualMachines"},
+			},
+			expect: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/b/providers/Microsoft.Network/virtualNetworks/b-1/subnets/b-2",
+		},
+		{
+			name: "subscription scope",
+			allowed: []ArmIDResource{
+				{Type: "Microsoft.Authorization/policyDefinitions", Scopes: []string{"Subscription"}},
+			},
+			expect: "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Authorization/policyDefinitions/b-1",
+		},
+		{
+			name: "tenant scope",
+			allowed: []ArmIDResource{
+				{Type: "Microsoft.Management/managementGroups", Scopes: []string{"Tenant"}},
+			},
+			expect: "/providers/Microsoft.Management/managementGroups/b-1",
+		},
+		{
+			name: "management group scope",
+			allowed: []ArmIDResource{
+				{Type: "Microsoft.Authorization/policyDefinitions", Scopes: []string{"ManagementGroup"}},
+			},
+			expect: "/providers/Microsoft.Management/managementGroups/b/providers/Microsoft.Authorization/policyDefinitions/b-1",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rnd := NewRnd(nil)
+			require.Equal(t, tt.expect, rnd.NextArmID(tt.allowed))
+		})
+	}
+}
@@ -54,7 +54,34 @@ func RResolveResponse(ref spec.Ref, visitedRefs map[string]bool, inputIsRef bool
 // Only when it is normally ended, the final schema, its pointing reference and all the visited references are returned.
 // Note that the visited references only include explicitly defined reference during the reference following, which doesn't include the input ref, unless explicitly mark the input is ref via the last param.
 func RResolve(ref spec.Ref, visitedRefs map[string]bool, inputIsRef bool) (*spec.Schema, spec.Ref, map[string]bool, bool, error) {
+	counts := map[string]int{}
+	for k, v := range visitedRefs {
+		if v {
+			counts[k] = 1
+		}
+	}
+	schema, ownRef, counts, ok, err := RResolveBounded(ref, counts, inputIsRef, nil)
+	if err != nil || !ok {
+		return nil, spec.Ref{}, nil, ok, err
+	}
 	visited := map[string]bool{}
+	for k := range counts {
+		visited[k] = true
+	}
+	return schema, ownRef, visited, true, nil
+}
+
+// DepthFunc returns the maximum times that a reference can be visited along a resolving path.
+type DepthFunc func(ref string) int
+
+// RResolveBounded is similar to RResolve, except the visited references are counted.
+// A reference is regarded as hit only when it has been visited for the times returned by the depth function (nil means 1 for every reference).
+func RResolveBounded(ref spec.Ref, visitedRefs map[string]int, inputIsRef bool, depth DepthFunc) (*spec.Schema, spec.Ref, map[string]int, bool, error) {
+	if depth == nil {
+		depth = func(string) int { return 1 }
+	}
+
+	visited := map[string]int{}
 	for k, v := range visitedRefs {
 		visited[k] = v
 	}
@@ -63,11 +90,11 @@ func RResolve(ref spec.Ref, visitedRefs map[string]bool, inputIsRef bool) (*spec
 		return nil, spec.Ref{}, nil, false, fmt.Errorf("Only normalized reference is allowed")
 	}
 
-	if _, ok := visited[ref.String()]; ok {
+	if visited[ref.String()] >= depth(ref.String()) {
 		return nil, spec.Ref{}, nil, false, nil
 	}
 	if inputIsRef {
-		visited[ref.String()] = true
+		visited[ref.String()]++
 	}
 
 	for {
@@ -85,9 +112,9 @@ func RResolve(ref spec.Ref, visitedRefs map[string]bool, inputIsRef bool) (*spec
 			return nil, spec.Ref{}, nil, false, err
 		}
 
-		if _, ok := visited[ref.String()]; ok {
+		if visited[ref.String()] >= depth(ref.String()) {
 			return nil, spec.Ref{}, nil, false, nil
 		}
-		visited[ref.String()] = true
+		visited[ref.String()]++
 	}
 }
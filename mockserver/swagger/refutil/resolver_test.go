@@ -195,3 +195,55 @@ func TestRResolveResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestRResolveBounded(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	specpathA := filepath.Join(pwd, "testdata", "a.json")
+
+	cases := []struct {
+		name       string
+		ref        string
+		visited    map[string]int
+		depth      DepthFunc
+		outVisited map[string]int
+		outOK      bool
+	}{
+		{
+			name: "#/definitions/Model1 (visited)",
+			ref:  specpathA + "#/definitions/Model1",
+			visited: map[string]int{
+				specpathA + "#/definitions/ConcreteModel": 1,
+			},
+			outOK: false,
+		},
+		{
+			name: "#/definitions/Model1 (visited within depth)",
+			ref:  specpathA + "#/definitions/Model1",
+			visited: map[string]int{
+				specpathA + "#/definitions/ConcreteModel": 1,
+			},
+			depth: func(string) int { return 2 },
+			outVisited: map[string]int{
+				specpathA + "#/definitions/ConcreteModel": 2,
+			},
+			outOK: true,
+		},
+		{
+			name:  "#/definitions/Circle1",
+			ref:   specpathA + "#/definitions/Circle1",
+			depth: func(string) int { return 3 },
+			outOK: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, visited, ok, err := RResolveBounded(spec.MustCreateRef(tt.ref), tt.visited, false, tt.depth)
+			require.NoError(t, err)
+			require.Equal(t, tt.outOK, ok)
+			require.Equal(t, tt.outVisited, visited)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package swagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-openapi/loads"
+	"github.com/magodo/azure-rest-api-bridge/log"
+)
+
+// onceCache is a concurrency safe cache, whose value of each key is only loaded once.
+type onceCache[T any] struct {
+	m sync.Map
+}
+
+type onceCacheEntry[T any] struct {
+	once sync.Once
+	v    T
+	err  error
+}
+
+func (c *onceCache[T]) get(key string, load func() (T, error)) (T, error) {
+	e, _ := c.m.LoadOrStore(key, &onceCacheEntry[T]{})
+	entry := e.(*onceCacheEntry[T])
+	entry.once.Do(func() {
+		entry.v, entry.err = load()
+	})
+	return entry.v, entry.err
+}
+
+var (
+	specDocCache     onceCache[*loads.Document]
+	specClosureCache onceCache[[]string]
+	variantMapCache  onceCache[VariantMap]
+)
+
+// loadSpec loads the swagger spec document of the path, which is shared among all the expanders.
+// The returned document must not be modified.
+func loadSpec(path string) (*loads.Document, error) {
+	return specDocCache.get(path, func() (*loads.Document, error) {
+		return loads.Spec(path)
+	})
+}
+
+// loadVariantMap loads the VariantMap of the swagger spec of the path, which is shared among all the expanders.
+func loadVariantMap(path string) (VariantMap, error) {
+	return variantMapCache.get(path, func() (VariantMap, error) {
+		return NewVariantMap(path)
+	})
+}
+
+// loadSpecClosure returns the paths of the swagger spec of the path, together with all the specs that are reachable from it via $ref (in sorted order).
+func loadSpecClosure(path string) ([]string, error) {
+	return specClosureCache.get(path, func() ([]string, error) {
+		return specClosure(path)
+	})
+}
+
+func specClosure(path string) ([]string, error) {
+	visited := map[string]bool{}
+	wl := []string{filepath.Clean(path)}
+	for len(wl) != 0 {
+		p := wl[0]
+		wl = wl[1:]
+		if visited[p] {
+			continue
+		}
+		doc, err := loadSpec(p)
+		if err != nil {
+			if p == filepath.Clean(path) {
+				return nil, err
+			}
+			// Tolerate the broken references that might never be followed by the expansion.
+			log.Warn("loading referenced spec", "path", p, "error", err)
+			continue
+		}
+		visited[p] = true
+		var raw interface{}
+		if err := json.Unmarshal(doc.Raw(), &raw); err != nil {
+			return nil, fmt.Errorf("unmarshalling %s: %v", p, err)
+		}
+		for _, ref := range collectRefs(raw) {
+			f := strings.SplitN(ref, "#", 2)[0]
+			if f == "" || strings.Contains(f, "://") {
+				continue
+			}
+			if !filepath.IsAbs(f) {
+				f = filepath.Join(filepath.Dir(p), f)
+			}
+			wl = append(wl, filepath.Clean(f))
+		}
+	}
+	files := make([]string, 0, len(visited))
+	for f := range visited {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// collectRefs collects the values of all the "$ref" in the JSON value.
+func collectRefs(v interface{}) []string {
+	var refs []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			if ref, ok := vv.(string); ok && k == "$ref" {
+				refs = append(refs, ref)
+				continue
+			}
+			refs = append(refs, collectRefs(vv)...)
+		}
+	case []interface{}:
+		for _, vv := range v {
+			refs = append(refs, collectRefs(vv)...)
+		}
+	}
+	return refs
+}
@@ -1,21 +1,64 @@
 package swagger
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
+
+	"github.com/go-openapi/spec"
+	"github.com/magodo/azure-rest-api-bridge/log"
 )
 
 type Synthesizer struct {
 	root *Property
 	rnd  *Rnd
 
-	useEnumValues     bool
-	duplicateElements map[string]int
+	useEnumValues        bool
+	arrayElementVariants bool
+	duplicateElements    map[string]int
+	values               map[string]SynthValue
+	mapKeys              map[string]SynthMapKey
+	behavior             SynthBehavior
+	propertyBehaviors    map[string]SynthBehavior
 }
 
 type SynthesizerOption struct {
-	UseEnumValues     bool
-	DuplicateElements []SynthDuplicateElement
+	UseEnumValues bool
+	// Synthesize one element per variant for the array whose element is polymorphic, instead of requiring the array element being monomorphisized.
+	// The input property is expected to be monomorphisized with the MonomorphizationOption.KeepArrayElementVariants.
+	ArrayElementVariants bool
+	DuplicateElements    []SynthDuplicateElement
+	Values               []SynthValue
+	MapKeys              []SynthMapKey
+	// Behavior applies to all the properties, unless overridden by PropertyBehaviors.
+	Behavior          SynthBehavior
+	PropertyBehaviors []SynthPropertyBehavior
+}
+
+// SynthBehavior controls how a property and its descendants are synthesized.
+type SynthBehavior struct {
+	// NullableAsNull emits null for the properties marked as "x-nullable".
+	NullableAsNull bool
+	// OmitOptional omits the properties that are not required by their parent object.
+	OmitOptional bool
+	// UseDefault emits the default value defined in the schema, if any.
+	UseDefault bool
+	// Access only emits the properties of the specified access, if specified.
+	Access SynthAccess
+}
+
+type SynthAccess string
+
+const (
+	SynthAccessAll      SynthAccess = ""
+	SynthAccessReadOnly SynthAccess = "readonly"
+	SynthAccessWritable SynthAccess = "writable"
+)
+
+// SynthPropertyBehavior overrides the synthesizer's behavior for the property specified by Addr and all its descendants.
+type SynthPropertyBehavior struct {
+	Addr     PropertyAddr
+	Behavior SynthBehavior
 }
 
 type SynthDuplicateElement struct {
@@ -23,28 +66,112 @@ type SynthDuplicateElement struct {
 	Addr PropertyAddr
 }
 
+// SynthValue pins the value of the property specified by Addr.
+// If Generator is nil, the Literal is used as the value (even if it is nil). Otherwise, the value is generated by the Generator.
+type SynthValue struct {
+	Addr      PropertyAddr
+	Literal   interface{}
+	Generator *SynthValueGenerator
+}
+
+// SynthMapKey specifies how to generate the keys of the map property specified by Addr.
+// The Keys are used in order at first, then the remaining keys are generated by the Format, or uniquely if Unique is true.
+// Otherwise, the remaining keys fallback to the default keys ("KEY", "KEY1", "KEY2", ...).
+// The Prefix is prepended to each of the keys.
+type SynthMapKey struct {
+	Addr   PropertyAddr
+	Keys   []string
+	Format string
+	Unique bool
+	Prefix string
+}
+
+// SynthValueGenerator generates the value for a primitive property, which takes precedence over the default generation.
+type SynthValueGenerator struct {
+	// EnumIndex picks up the enum value at this index. It conflicts with the other fields.
+	EnumIndex *int
+	// Format overrides the format defined in the schema.
+	Format string
+	// Prefix is prepended to the generated value, only applies to string properties.
+	Prefix string
+}
+
 func NewSynthesizer(root *Property, rnd *Rnd, opt *SynthesizerOption) (*Synthesizer, error) {
-	if !root.IsMono() {
-		return nil, fmt.Errorf("property is not monomorphisized")
-	}
 	if opt == nil {
 		opt = &SynthesizerOption{}
 	}
+	if !root.isMono(opt.ArrayElementVariants) {
+		return nil, fmt.Errorf("property is not monomorphisized")
+	}
 	dem := map[string]int{}
 	for _, de := range opt.DuplicateElements {
 		dem[de.Addr.String()] = de.Cnt
 	}
+	vm := map[string]SynthValue{}
+	for _, v := range opt.Values {
+		vm[v.Addr.String()] = v
+	}
+	mkm := map[string]SynthMapKey{}
+	for _, mk := range opt.MapKeys {
+		mkm[mk.Addr.String()] = mk
+	}
+	pbm := map[string]SynthBehavior{}
+	for _, pb := range opt.PropertyBehaviors {
+		pbm[pb.Addr.String()] = pb.Behavior
+	}
 	return &Synthesizer{
-		root:              root,
-		rnd:               rnd,
-		useEnumValues:     opt.UseEnumValues,
-		duplicateElements: dem,
+		root:                 root,
+		rnd:                  rnd,
+		useEnumValues:        opt.UseEnumValues,
+		arrayElementVariants: opt.ArrayElementVariants,
+		duplicateElements:    dem,
+		values:               vm,
+		mapKeys:              mkm,
+		behavior:             opt.Behavior,
+		propertyBehaviors:    pbm,
 	}, nil
 }
 
+// synthContext is the context passed down from a property to its descendants during synthesizing.
+type synthContext struct {
+	behavior SynthBehavior
+	// Whether the property or any of its ancestors is readOnly
+	readOnly bool
+}
+
 func (syn *Synthesizer) Synthesize() (interface{}, bool) {
-	var synProp func(parent, p *Property) (interface{}, bool)
-	synProp = func(parent, p *Property) (interface{}, bool) {
+	var synProp func(parent, p *Property, sctx synthContext) (interface{}, bool)
+	synProp = func(parent, p *Property, sctx synthContext) (interface{}, bool) {
+		if b, ok := syn.propertyBehaviors[p.addr.String()]; ok {
+			sctx.behavior = b
+		}
+		if p.Schema != nil && p.Schema.ReadOnly {
+			sctx.readOnly = true
+		}
+
+		if v, ok := syn.values[p.addr.String()]; ok && !isDiscriminatorProp(parent, p) {
+			if v.Generator == nil {
+				return v.Literal, true
+			}
+			if val, ok := syn.generate(p, *v.Generator); ok {
+				return val, true
+			}
+		}
+
+		if p.Schema != nil && !isDiscriminatorProp(parent, p) {
+			if sctx.behavior.NullableAsNull {
+				if nullable, _ := p.Schema.Extensions.GetBool("x-nullable"); nullable {
+					return nil, true
+				}
+			}
+			if sctx.behavior.UseDefault && p.Schema.Default != nil {
+				return p.Schema.Default, true
+			}
+		}
+
+		// Whether to omit the container property if all of its content are filtered out by the access
+		omitEmpty := sctx.behavior.Access != SynthAccessAll && parent != nil
+
 		switch {
 		case p.Element != nil:
 			n := 1
@@ -53,24 +180,47 @@ func (syn *Synthesizer) Synthesize() (interface{}, bool) {
 			}
 
 			var elements []interface{}
+			if syn.arrayElementVariants && p.isPolymorphicArray() {
+				keys := make([]string, 0, len(p.Element.Variant))
+				for k := range p.Element.Variant {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					// Only the first instance of each variant is synthesized. It never errors as no variant is fixed.
+					instances, _ := Monomorphization(p.Element.Variant[k], &MonomorphizationOption{KeepArrayElementVariants: true})
+					if len(instances) == 0 {
+						continue
+					}
+					elem := *p.Element
+					elem.Variant = map[string]*Property{k: &instances[0]}
+					for i := 0; i < n; i++ {
+						if inner, ok := synProp(p, &elem, sctx); ok {
+							elements = append(elements, inner)
+						}
+					}
+				}
+				if len(elements) == 0 && omitEmpty {
+					return nil, false
+				}
+				return elements, true
+			}
 			for i := 0; i < n; i++ {
-				if inner, ok := synProp(p, p.Element); ok {
+				if inner, ok := synProp(p, p.Element, sctx); ok {
 					elements = append(elements, inner)
 				}
 			}
+			if len(elements) == 0 && omitEmpty {
+				return nil, false
+			}
 
 			if SchemaIsArray(p.Schema) {
 				return elements, true
 			} else {
 				// map
 				res := map[string]interface{}{}
-				for i := 0; i < n; i++ {
-					key := "KEY"
-					if i != 0 {
-						key = fmt.Sprintf("KEY%d", i)
-					}
-					inner := elements[i]
-					res[key] = inner
+				for i, inner := range elements {
+					res[syn.mapKey(p, i)] = inner
 				}
 				return res, true
 			}
@@ -86,36 +236,48 @@ func (syn *Synthesizer) Synthesize() (interface{}, bool) {
 				}
 				sort.Strings(keys)
 				for _, k := range keys {
-					if v, ok := synProp(p, p.Children[k]); ok {
+					child := p.Children[k]
+					if syn.omitChild(p, child, sctx) {
+						continue
+					}
+					if v, ok := synProp(p, child, sctx); ok {
 						res[k] = v
 					}
 				}
+				if len(res) == 0 && omitEmpty {
+					return nil, false
+				}
 				return res, true
 			}
 		case p.Variant != nil:
 			for _, v := range p.Variant {
 				// There must be at most one variant
-				return synProp(p, v)
+				return synProp(p, v, sctx)
 			}
 		default:
 			if p.Schema == nil {
 				return nil, false
 			}
-			if len(p.Schema.Type) != 1 {
-				panic(fmt.Sprintf("%s: schema type as array is not supported", *p))
+			if isDiscriminatorProp(parent, p) {
+				// discriminator property
+				return parent.DiscriminatorValue, true
+			}
+			switch sctx.behavior.Access {
+			case SynthAccessReadOnly:
+				if !sctx.readOnly {
+					return nil, false
+				}
+			case SynthAccessWritable:
+				if sctx.readOnly {
+					return nil, false
+				}
 			}
-			switch t := p.Schema.Type[0]; t {
+			switch t := leafSchemaType(p.Schema); t {
 			case "string":
-				if parent != nil && parent.Discriminator != "" && parent.Discriminator == p.Name() {
-					// discriminator property
-					return parent.DiscriminatorValue, true
+				if syn.useEnumValues && len(p.Schema.Enum) != 0 {
+					return p.Schema.Enum[0].(string), true
 				} else {
-					// regular string
-					if syn.useEnumValues && len(p.Schema.Enum) != 0 {
-						return p.Schema.Enum[0].(string), true
-					} else {
-						return syn.rnd.NextString(p.Schema.Format), true
-					}
+					return syn.nextString(p, p.Schema.Format), true
 				}
 			case "file":
 				return syn.rnd.NextString(p.Schema.Format), true
@@ -125,6 +287,8 @@ func (syn *Synthesizer) Synthesize() (interface{}, bool) {
 				return syn.rnd.NextNumber(p.Schema.Format), true
 			case "boolean":
 				return true, true
+			case "null":
+				return nil, true
 			case "object", "", "array":
 				// Returns nothing as this implies there is a circular ref hit
 				return nil, false
@@ -135,5 +299,132 @@ func (syn *Synthesizer) Synthesize() (interface{}, bool) {
 		panic("unreachable")
 	}
 
-	return synProp(nil, syn.root)
+	return synProp(nil, syn.root, synthContext{behavior: syn.behavior})
+}
+
+// omitChild tells whether to omit the child property of an object, based on the behavior.
+// The discriminator property and the pinned properties are never omitted.
+func (syn *Synthesizer) omitChild(parent, child *Property, sctx synthContext) bool {
+	if b, ok := syn.propertyBehaviors[child.addr.String()]; ok {
+		sctx.behavior = b
+	}
+	if !sctx.behavior.OmitOptional || child.Meta.Required || isDiscriminatorProp(parent, child) {
+		return false
+	}
+	if _, ok := syn.values[child.addr.String()]; ok {
+		return false
+	}
+	return true
+}
+
+// mapKey returns the i-th key of the map property p.
+func (syn *Synthesizer) mapKey(p *Property, i int) string {
+	defaultKey := func() string {
+		if i == 0 {
+			return "KEY"
+		}
+		return fmt.Sprintf("KEY%d", i)
+	}
+	mk, ok := syn.mapKeys[p.addr.String()]
+	if !ok {
+		return defaultKey()
+	}
+	switch {
+	case i < len(mk.Keys):
+		return mk.Prefix + mk.Keys[i]
+	case mk.Format != "":
+		return mk.Prefix + syn.rnd.NextString(mk.Format)
+	case mk.Unique:
+		return mk.Prefix + "KEY-" + syn.rnd.NextString("")
+	default:
+		return mk.Prefix + defaultKey()
+	}
+}
+
+// generate generates the value of a primitive property by the generator. It returns false if the generator doesn't apply to the property.
+func (syn *Synthesizer) generate(p *Property, gen SynthValueGenerator) (interface{}, bool) {
+	if p.Schema == nil || len(p.Schema.Type) != 1 {
+		log.Warn("value generator only applies to primitive properties", "addr", p.addr.String())
+		return nil, false
+	}
+	if gen.EnumIndex != nil {
+		idx := *gen.EnumIndex
+		if idx < 0 || idx >= len(p.Schema.Enum) {
+			log.Warn("enum index of the value generator is out of range", "addr", p.addr.String(), "index", idx, "enum", p.Schema.Enum)
+			return nil, false
+		}
+		return p.Schema.Enum[idx], true
+	}
+	format := p.Schema.Format
+	if gen.Format != "" {
+		format = gen.Format
+	}
+	switch p.Schema.Type[0] {
+	case "string", "file":
+		return gen.Prefix + syn.nextString(p, format), true
+	case "integer":
+		return syn.rnd.NextInteger(format), true
+	case "number":
+		return syn.rnd.NextNumber(format), true
+	default:
+		log.Warn("value generator only applies to primitive properties", "addr", p.addr.String())
+		return nil, false
+	}
+}
+
+// nextString generates the next string value of the property in the specified format.
+func (syn *Synthesizer) nextString(p *Property, format string) string {
+	if format == "arm-id" {
+		return syn.rnd.NextArmID(armIDAllowedResources(p.Schema))
+	}
+	return syn.rnd.NextString(format)
+}
+
+// armIDAllowedResources returns the allowed resources defined in the "x-ms-arm-id-details" of the schema.
+func armIDAllowedResources(schema *spec.Schema) []ArmIDResource {
+	if schema == nil {
+		return nil
+	}
+	v, ok := schema.Extensions["x-ms-arm-id-details"]
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var details struct {
+		AllowedResources []struct {
+			Type   string   `json:"type"`
+			Scopes []string `json:"scopes"`
+		} `json:"allowedResources"`
+	}
+	if err := json.Unmarshal(b, &details); err != nil {
+		log.Warn("invalid x-ms-arm-id-details", "error", err)
+		return nil
+	}
+	var out []ArmIDResource
+	for _, r := range details.AllowedResources {
+		out = append(out, ArmIDResource{Type: r.Type, Scopes: r.Scopes})
+	}
+	return out
+}
+
+// isDiscriminatorProp tells whether the property p is the discriminator property of its (variant) parent.
+func isDiscriminatorProp(parent, p *Property) bool {
+	return parent != nil && parent.Discriminator != "" && parent.Discriminator == p.Name()
+}
+
+// leafSchemaType returns the type of a leaf schema. The multiple (non-null) types are expected to be expanded as variants by the expander,
+// so that the first non-null type is returned, or "null" if all the types are null, or "" if no type is specified.
+func leafSchemaType(schema *spec.Schema) string {
+	if len(schema.Type) == 0 {
+		return ""
+	}
+	for _, t := range schema.Type {
+		if t != "null" {
+			return t
+		}
+	}
+	return "null"
 }
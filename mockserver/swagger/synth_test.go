@@ -16,10 +16,12 @@ func TestSynthesize(t *testing.T) {
 	specpathSyn := filepath.Join(pwd, "testdata", "syn.json")
 
 	cases := []struct {
-		name   string
-		ref    string
-		opt    *SynthesizerOption
-		expect []string
+		name    string
+		ref     string
+		opt     *SynthesizerOption
+		monoOpt *MonomorphizationOption
+		expOpt  *ExpanderOption
+		expect  []string
 	}{
 		{
 			name: specpathSyn + "#/definitions/object",
@@ -96,6 +98,107 @@ func TestSynthesize(t *testing.T) {
 						`,
 			},
 		},
+		{
+			name: specpathSyn + "#/definitions/object (value)",
+			ref:  specpathSyn + "#/definitions/object",
+			opt: &SynthesizerOption{
+				Values: []SynthValue{
+					{
+						Addr:    MustParseAddr("string"),
+						Literal: "foo",
+					},
+					{
+						Addr:    MustParseAddr("integer"),
+						Literal: float64(10),
+					},
+					{
+						Addr:      MustParseAddr("object/p1"),
+						Generator: &SynthValueGenerator{Prefix: "pre-"},
+					},
+					{
+						Addr:      MustParseAddr("map/*"),
+						Generator: &SynthValueGenerator{Format: "uuid"},
+					},
+				},
+			},
+			expect: []string{
+				`
+		{
+		  "array": [
+		    "b"
+		  ],
+		  "boolean": true,
+		  "emptyObject": {},
+		  "integer": 10,
+		  "map": {
+		    "KEY": "00000000-0000-0000-0000-000000000001"
+		  },
+		  "map2": {
+		    "KEY": "c"
+		  },
+		  "number": 1.5,
+		  "object": {
+		  	"p1": "pre-d",
+			"obj": {
+				"pp1": 2
+			}
+		  },
+		  "string": "foo"
+		}
+						`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/object (map key)",
+			ref:  specpathSyn + "#/definitions/object",
+			opt: &SynthesizerOption{
+				DuplicateElements: []SynthDuplicateElement{
+					{
+						Cnt:  1,
+						Addr: MustParseAddr("map"),
+					},
+				},
+				MapKeys: []SynthMapKey{
+					{
+						Addr:   MustParseAddr("map"),
+						Keys:   []string{"foo"},
+						Format: "uuid",
+					},
+					{
+						Addr:   MustParseAddr("map2"),
+						Unique: true,
+						Prefix: "x-ms-meta-",
+					},
+				},
+			},
+			expect: []string{
+				`
+		{
+		  "array": [
+		    "b"
+		  ],
+		  "boolean": true,
+		  "emptyObject": {},
+		  "integer": 1,
+		  "map": {
+		    "foo": "c",
+		    "00000000-0000-0000-0000-000000000002": "d"
+		  },
+		  "map2": {
+		    "x-ms-meta-KEY-f": "e"
+		  },
+		  "number": 1.5,
+		  "object": {
+		  	"p1": "g",
+			"obj": {
+				"pp1": 3
+			}
+		  },
+		  "string": "h"
+		}
+						`,
+			},
+		},
 		{
 			name: specpathSyn + "#/definitions/base",
 			ref:  specpathSyn + "#/definitions/base",
@@ -160,6 +263,55 @@ func TestSynthesize(t *testing.T) {
 						`,
 			},
 		},
+		{
+			name: specpathSyn + "#/definitions/enumobject (value)",
+			ref:  specpathSyn + "#/definitions/enumobject",
+			opt: &SynthesizerOption{
+				Values: []SynthValue{
+					{
+						Addr:      MustParseAddr("prop"),
+						Generator: &SynthValueGenerator{EnumIndex: ptr(1)},
+					},
+				},
+			},
+			expect: []string{
+				`
+		{
+			"prop": "bar"
+		}
+						`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/base (value)",
+			ref:  specpathSyn + "#/definitions/base",
+			opt: &SynthesizerOption{
+				Values: []SynthValue{
+					{
+						Addr:    MustParseAddr("{var2}/prop2"),
+						Literal: "foo",
+					},
+					{
+						Addr:    MustParseAddr("{var2}/type"),
+						Literal: "bar",
+					},
+				},
+			},
+			expect: []string{
+				`
+		{
+			"type": "var1",
+			"prop1": "b"
+		}
+						`,
+				`
+		{
+			"type": "var2",
+			"prop2": "foo"
+		}
+						`,
+			},
+		},
 		{
 			name: specpathSyn + "#/definitions/use_base",
 			ref:  specpathSyn + "#/definitions/use_base",
@@ -254,6 +406,84 @@ func TestSynthesize(t *testing.T) {
 						`,
 			},
 		},
+		{
+			name: specpathSyn + "#/definitions/array_of_base",
+			ref:  specpathSyn + "#/definitions/array_of_base",
+			expect: []string{
+				`
+		[
+			{
+				"type": "var1",
+				"prop1": "b"
+			}
+		]
+						`,
+				`
+		[
+			{
+				"type": "var2",
+				"prop2": "b"
+			}
+		]
+						`,
+			},
+		},
+		{
+			name:    specpathSyn + "#/definitions/array_of_base (array element variants)",
+			ref:     specpathSyn + "#/definitions/array_of_base",
+			opt:     &SynthesizerOption{ArrayElementVariants: true},
+			monoOpt: &MonomorphizationOption{KeepArrayElementVariants: true},
+			expect: []string{
+				`
+		[
+			{
+				"type": "var1",
+				"prop1": "b"
+			},
+			{
+				"type": "var2",
+				"prop2": "c"
+			}
+		]
+						`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/array_of_base (array element variants with duplicate)",
+			ref:  specpathSyn + "#/definitions/array_of_base",
+			opt: &SynthesizerOption{
+				ArrayElementVariants: true,
+				DuplicateElements: []SynthDuplicateElement{
+					{
+						Cnt:  1,
+						Addr: MustParseAddr(""),
+					},
+				},
+			},
+			monoOpt: &MonomorphizationOption{KeepArrayElementVariants: true},
+			expect: []string{
+				`
+		[
+			{
+				"type": "var1",
+				"prop1": "b"
+			},
+			{
+				"type": "var1",
+				"prop1": "c"
+			},
+			{
+				"type": "var2",
+				"prop2": "d"
+			},
+			{
+				"type": "var2",
+				"prop2": "e"
+			}
+		]
+						`,
+			},
+		},
 		{
 			name: specpathSyn + "#/definitions/conflictbase",
 			ref:  specpathSyn + "#/definitions/conflictbase",
@@ -312,15 +542,291 @@ func TestSynthesize(t *testing.T) {
 			`,
 			},
 		},
+		{
+			name: specpathSyn + "#/definitions/sparse",
+			ref:  specpathSyn + "#/definitions/sparse",
+			expect: []string{
+				`
+		{
+		  "def": 1,
+		  "nullable": "b",
+		  "obj": {
+		    "ro": "c"
+		  },
+		  "opt": "d",
+		  "req": "e",
+		  "ro": "f",
+		  "roObj": {
+		    "p": "g"
+		  }
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/sparse (nullable and default)",
+			ref:  specpathSyn + "#/definitions/sparse",
+			opt: &SynthesizerOption{
+				Behavior: SynthBehavior{
+					NullableAsNull: true,
+					UseDefault:     true,
+				},
+			},
+			expect: []string{
+				`
+		{
+		  "def": 3,
+		  "nullable": null,
+		  "obj": {
+		    "ro": "b"
+		  },
+		  "opt": "c",
+		  "req": "d",
+		  "ro": "e",
+		  "roObj": {
+		    "p": "f"
+		  }
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/sparse (omit optional)",
+			ref:  specpathSyn + "#/definitions/sparse",
+			opt: &SynthesizerOption{
+				Behavior: SynthBehavior{
+					OmitOptional: true,
+				},
+				Values: []SynthValue{
+					{
+						Addr:    MustParseAddr("opt"),
+						Literal: "pinned",
+					},
+				},
+			},
+			expect: []string{
+				`
+		{
+		  "opt": "pinned",
+		  "req": "b"
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/sparse (readonly)",
+			ref:  specpathSyn + "#/definitions/sparse",
+			opt: &SynthesizerOption{
+				Behavior: SynthBehavior{
+					Access: SynthAccessReadOnly,
+				},
+			},
+			expect: []string{
+				`
+		{
+		  "obj": {
+		    "ro": "b"
+		  },
+		  "ro": "c",
+		  "roObj": {
+		    "p": "d"
+		  }
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/sparse (property behavior)",
+			ref:  specpathSyn + "#/definitions/sparse",
+			opt: &SynthesizerOption{
+				Behavior: SynthBehavior{
+					Access: SynthAccessWritable,
+				},
+				PropertyBehaviors: []SynthPropertyBehavior{
+					{
+						Addr: MustParseAddr("roObj"),
+					},
+				},
+			},
+			expect: []string{
+				`
+		{
+		  "def": 1,
+		  "nullable": "b",
+		  "opt": "c",
+		  "req": "d",
+		  "roObj": {
+		    "p": "e"
+		  }
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/armid",
+			ref:  specpathSyn + "#/definitions/armid",
+			expect: []string{
+				`
+		{
+		  "id": "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/b/providers/Microsoft.Network/virtualNetworks/b-1/subnets/b-2"
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/nulltype",
+			ref:  specpathSyn + "#/definitions/nulltype",
+			expect: []string{
+				`
+		{
+		  "null": null,
+		  "nulls": null,
+		  "str": "b"
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/multitype",
+			ref:  specpathSyn + "#/definitions/multitype",
+			expect: []string{
+				`
+		{
+		  "multi": 1,
+		  "nullable": "b",
+		  "obj": [
+		    "c"
+		  ]
+		}
+				`,
+				`
+		{
+		  "multi": "b",
+		  "nullable": "c",
+		  "obj": [
+		    "d"
+		  ]
+		}
+				`,
+				`
+		{
+		  "multi": 1,
+		  "nullable": "b",
+		  "obj": {
+		    "p": "c"
+		  }
+		}
+				`,
+				`
+		{
+		  "multi": "b",
+		  "nullable": "c",
+		  "obj": {
+		    "p": "d"
+		  }
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/oneof",
+			ref:  specpathSyn + "#/definitions/oneof",
+			expect: []string{
+				`
+		{
+		  "bark": "b",
+		  "common": "c"
+		}
+				`,
+				`
+		{
+		  "common": "b",
+		  "meow": "c"
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/anyof",
+			ref:  specpathSyn + "#/definitions/anyof",
+			expect: []string{
+				`
+		{
+		  "v": "b"
+		}
+				`,
+				`
+		{
+		  "v": 1
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/recursive",
+			ref:  specpathSyn + "#/definitions/recursive",
+			expect: []string{
+				`
+		{
+		  "name": "b"
+		}
+				`,
+			},
+		},
+		{
+			name:   specpathSyn + "#/definitions/recursive (recursion depth)",
+			ref:    specpathSyn + "#/definitions/recursive",
+			expOpt: &ExpanderOption{RecursionDepth: 3},
+			expect: []string{
+				`
+		{
+		  "children": [
+		    {
+		      "children": [
+		        {
+		          "name": "b"
+		        }
+		      ],
+		      "name": "c"
+		    }
+		  ],
+		  "name": "d"
+		}
+				`,
+			},
+		},
+		{
+			name: specpathSyn + "#/definitions/recursive (ref recursion depth)",
+			ref:  specpathSyn + "#/definitions/recursive",
+			expOpt: &ExpanderOption{
+				RecursionDepth: 3,
+				RefRecursionDepths: map[string]int{
+					specpathSyn + "#/definitions/recursive": 2,
+				},
+			},
+			expect: []string{
+				`
+		{
+		  "children": [
+		    {
+		      "name": "b"
+		    }
+		  ],
+		  "name": "c"
+		}
+				`,
+			},
+		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			ref := spec.MustCreateRef(tt.ref)
-			exp, err := NewExpander(ref, nil)
+			exp, err := NewExpander(ref, tt.expOpt)
 			require.NoError(t, err)
 			require.NoError(t, exp.Expand())
-			propInstances := Monomorphization(exp.Root())
+			propInstances, err := Monomorphization(exp.Root(), tt.monoOpt)
+			require.NoError(t, err)
 			require.Len(t, propInstances, len(tt.expect))
 			for i, v := range propInstances {
 				propInstance := v
@@ -1,11 +1,15 @@
 package swagger
 
 import (
-	"github.com/go-openapi/loads"
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/go-openapi/spec"
 	"github.com/magodo/azure-rest-api-bridge/mockserver/swagger/refutil"
 )
 
-// VariantMap maps the x-ms-discriminator-value to the model name in "/definitions".
+// VariantMap maps the x-ms-discriminator-value to the model (normalized reference to the "/definitions").
 // Note that the variant map is the plain translation of the swagger inheritance strucutre, it doesn't take
 // cascaded variants into consideration. So always ensure use `Get()` to get the complete variant set of a model.
 type VariantMap map[string]VariantInfo
@@ -15,16 +19,16 @@ type VariantInfo struct {
 	VariantValueToModel map[string]string
 }
 
-func (m VariantMap) Get(modelName string) (*VariantInfo, bool) {
-	if _, ok := m[modelName]; !ok {
+func (m VariantMap) Get(model string) (*VariantInfo, bool) {
+	if _, ok := m[model]; !ok {
 		return nil, false
 	}
 	wl := []string{}
 	out := &VariantInfo{
-		Discriminator:       m[modelName].Discriminator,
+		Discriminator:       m[model].Discriminator,
 		VariantValueToModel: map[string]string{},
 	}
-	for vValue, vName := range m[modelName].VariantValueToModel {
+	for vValue, vName := range m[model].VariantValueToModel {
 		out.VariantValueToModel[vValue] = vName
 		wl = append(wl, vName)
 	}
@@ -49,16 +53,47 @@ func (m VariantMap) Get(modelName string) (*VariantInfo, bool) {
 	return out, true
 }
 
+// NewVariantMap builds the VariantMap over the swagger spec of the path, together with all the specs that are reachable from it via $ref,
+// so that the variants defined in a different file than their base model are also recognized.
+// The models are identified by their normalized references (e.g. "/path/to/spec.json#/definitions/Foo").
 func NewVariantMap(path string) (VariantMap, error) {
-	doc, err := loads.Spec(path)
+	files, err := loadSpecClosure(path)
 	if err != nil {
 		return nil, err
 	}
-	definitions := doc.Spec().Definitions
+	definitions := map[string]spec.Schema{}
+	names := map[string]string{}
+	for _, f := range files {
+		doc, err := loadSpec(f)
+		if err != nil {
+			return nil, err
+		}
+		for modelName, def := range doc.Spec().Definitions {
+			model := f + "#/definitions/" + jsonpointer.Escape(modelName)
+			definitions[model] = def
+			names[model] = modelName
+		}
+	}
+
+	// The normalized references of the allOf parents of each model
+	parents := map[string][]string{}
+	for model, def := range definitions {
+		for _, allOf := range def.AllOf {
+			if allOf.Ref.String() == "" {
+				continue
+			}
+			ref, err := refutil.NormalizeFileRef(allOf.Ref, strings.SplitN(model, "#", 2)[0])
+			if err != nil {
+				return nil, fmt.Errorf("normalizing allOf ref %s of %s: %v", allOf.Ref.String(), model, err)
+			}
+			parents[model] = append(parents[model], ref.String())
+		}
+	}
+
 	m := VariantMap{}
-	for modelName, def := range definitions {
+	for model, def := range definitions {
 		if def.Discriminator != "" {
-			m[modelName] = VariantInfo{
+			m[model] = VariantInfo{
 				Discriminator:       def.Discriminator,
 				VariantValueToModel: map[string]string{},
 			}
@@ -68,17 +103,13 @@ func NewVariantMap(path string) (VariantMap, error) {
 	toContinue := true
 	for toContinue {
 		toContinue = false
-		for modelName, def := range definitions {
-			if _, ok := m[modelName]; ok {
+		for model := range definitions {
+			if _, ok := m[model]; ok {
 				continue
 			}
-			for _, allOf := range def.AllOf {
-				if allOf.Ref.String() == "" {
-					continue
-				}
-				parent := refutil.Last(allOf.Ref.Ref)
+			for _, parent := range parents[model] {
 				if parentVariantInfo, ok := m[parent]; ok {
-					m[modelName] = VariantInfo{
+					m[model] = VariantInfo{
 						Discriminator:       parentVariantInfo.Discriminator,
 						VariantValueToModel: map[string]string{},
 					}
@@ -88,19 +119,15 @@ func NewVariantMap(path string) (VariantMap, error) {
 		}
 	}
 
-	for modelName, def := range definitions {
-		vname := modelName
+	for model, def := range definitions {
+		vname := names[model]
 		if v, ok := def.Extensions["x-ms-discriminator-value"]; ok {
 			vname = v.(string)
 		}
 
-		for _, allOf := range def.AllOf {
-			if allOf.Ref.String() == "" {
-				continue
-			}
-			parent := refutil.Last(allOf.Ref.Ref)
+		for _, parent := range parents[model] {
 			if varInfo, ok := m[parent]; ok {
-				varInfo.VariantValueToModel[vname] = modelName
+				varInfo.VariantValueToModel[vname] = model
 			}
 		}
 	}
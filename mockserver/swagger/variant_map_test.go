@@ -14,55 +14,97 @@ func TestVariantMapNew(t *testing.T) {
 	m, err := NewVariantMap(spec)
 	require.NoError(t, err)
 	require.Equal(t, VariantMap{
-		"Base": VariantInfo{
+		spec + "#/definitions/Base": VariantInfo{
 			Discriminator:       "type",
-			VariantValueToModel: map[string]string{"Var1": "Var1"},
+			VariantValueToModel: map[string]string{"Var1": spec + "#/definitions/Var1"},
 		},
-		"Var1": VariantInfo{
+		spec + "#/definitions/Var1": VariantInfo{
 			Discriminator: "type",
 			VariantValueToModel: map[string]string{
-				"Var2": "Var2",
+				"Var2": spec + "#/definitions/Var2",
 			},
 		},
-		"Var2": VariantInfo{
+		spec + "#/definitions/Var2": VariantInfo{
 			Discriminator:       "type",
 			VariantValueToModel: map[string]string{},
 		},
 	}, m)
 }
 
+func TestVariantMapNewCrossFile(t *testing.T) {
+	pwd, _ := os.Getwd()
+	root := filepath.Join(pwd, "testdata", "xfile", "root.json")
+	base := filepath.Join(pwd, "testdata", "xfile", "base.json")
+	variant := filepath.Join(pwd, "testdata", "xfile", "var.json")
+
+	cases := []struct {
+		name   string
+		path   string
+		expect map[string]string
+	}{
+		{
+			name: "from the root spec",
+			path: root,
+			expect: map[string]string{
+				"Dog":  base + "#/definitions/Dog",
+				"fish": variant + "#/definitions/Fish",
+			},
+		},
+		{
+			name: "from the base spec",
+			path: base,
+			expect: map[string]string{
+				"Dog": base + "#/definitions/Dog",
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewVariantMap(tt.path)
+			require.NoError(t, err)
+			info, ok := m.Get(base + "#/definitions/Pet")
+			require.True(t, ok)
+			require.Equal(t, &VariantInfo{
+				Discriminator:       "kind",
+				VariantValueToModel: tt.expect,
+			}, info)
+		})
+	}
+}
+
 func TestVariantMapGet(t *testing.T) {
 	pwd, _ := os.Getwd()
 	spec := filepath.Join(pwd, "testdata", "variant_map.json")
 	m, err := NewVariantMap(spec)
 	require.NoError(t, err)
-	mBase, ok := m.Get("Base")
+	mBase, ok := m.Get(spec + "#/definitions/Base")
 	require.Equal(t, true, ok)
 	require.Equal(t, &VariantInfo{
 		Discriminator: "type",
 		VariantValueToModel: map[string]string{
-			"Var1": "Var1",
-			"Var2": "Var2",
+			"Var1": spec + "#/definitions/Var1",
+			"Var2": spec + "#/definitions/Var2",
 		},
 	},
 		mBase)
-	mVar1, ok := m.Get("Var1")
+	mVar1, ok := m.Get(spec + "#/definitions/Var1")
 	require.Equal(t, true, ok)
 	require.Equal(t, &VariantInfo{
 		Discriminator: "type",
 		VariantValueToModel: map[string]string{
-			"Var2": "Var2",
+			"Var2": spec + "#/definitions/Var2",
 		},
 	},
 		mVar1)
-	mVar2, ok := m.Get("Var2")
+	mVar2, ok := m.Get(spec + "#/definitions/Var2")
 	require.Equal(t, true, ok)
 	require.Equal(t, &VariantInfo{
 		Discriminator:       "type",
 		VariantValueToModel: map[string]string{},
 	},
 		mVar2)
-	mNoVar, ok := m.Get("NoVar")
+	mNoVar, ok := m.Get(spec + "#/definitions/NoVar")
 	require.Equal(t, false, ok)
 	require.Nil(t, mNoVar)
 }